@@ -6,12 +6,18 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 	"github.com/pulkyeet/BookmarkD/internal/analytics"
 	"github.com/joho/godotenv"
 	"github.com/pulkyeet/BookmarkD/internal/cache"
+	"github.com/pulkyeet/BookmarkD/internal/covers"
 	"github.com/pulkyeet/BookmarkD/internal/database"
 	"github.com/pulkyeet/BookmarkD/internal/handlers"
 	"github.com/pulkyeet/BookmarkD/internal/middleware"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+	"github.com/pulkyeet/BookmarkD/internal/storage"
 )
 
 func main() {
@@ -43,9 +49,18 @@ func main() {
 	commentRepo := database.NewCommentRepository(db)
 	genreRepo := database.NewGenreRepository(db)
 	listRepo := database.NewListRepository(db)
+	searchRepo := database.NewSearchRepository(db)
+	authorRepo := database.NewAuthorRepository(db)
+	seriesRepo := database.NewSeriesRepository(db)
+	pendingEditRepo := database.NewPendingEditRepository(db)
+	duplicateRepo := database.NewDuplicateRepository(db)
+	tagRepo := database.NewTagRepository(db)
+	sessionRepo := database.NewReadingSessionRepository(db)
+	progressRepo := database.NewProgressRepository(db)
+	shelfRepo := database.NewShelfRepository(db)
 	
 	ratingHandler := handlers.NewRatingHandler(ratingRepo)
-	bookHandler := handlers.NewBookHandler(bookRepo)
+	bookHandler := handlers.NewBookHandler(bookRepo, pendingEditRepo)
 	authHandler := handlers.NewAuthHandler(userRepo)
 	authHandler.SetOAuthConfig(
 		os.Getenv("GOOGLE_CLIENT_ID"),
@@ -57,8 +72,42 @@ func main() {
 	commentHandler := handlers.NewCommentHandler(commentRepo)
 	genreHandler := handlers.NewGenreHandler(genreRepo)
 	listHandler := handlers.NewListHandler(listRepo)
-	importHandler := handlers.NewImportHandler(bookRepo, ratingRepo)
+	importHandler := handlers.NewImportHandler(bookRepo, ratingRepo, shelfRepo)
 	embedHandler := handlers.NewEmbedHandler(ratingRepo, listRepo, userRepo)
+	searchHandler := handlers.NewSearchHandler(searchRepo)
+	authorHandler := handlers.NewAuthorHandler(authorRepo, ratingRepo)
+	seriesHandler := handlers.NewSeriesHandler(seriesRepo)
+	tagHandler := handlers.NewTagHandler(tagRepo)
+	sessionHandler := handlers.NewReadingSessionHandler(sessionRepo)
+	progressHandler := handlers.NewProgressHandler(progressRepo)
+	shelfHandler := handlers.NewShelfHandler(shelfRepo)
+	// Popularity scores shrink towards this prior; see models.PopularityPrior
+	prior := models.DefaultPopularityPrior
+	if v, err := strconv.ParseFloat(os.Getenv("POPULARITY_PRIOR_WEIGHT"), 64); err == nil && v >= 0 {
+		prior.Weight = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("POPULARITY_PRIOR_MEAN"), 64); err == nil && v >= 1 && v <= 10 {
+		prior.Mean = v
+	}
+	if err := bookRepo.SetPopularityPrior(prior); err != nil {
+		log.Printf("Failed to set popularity prior: %v", err)
+	}
+	coverDir := os.Getenv("COVER_STORAGE_DIR")
+	if coverDir == "" {
+		coverDir = "./data/covers"
+	}
+	coverStore, err := storage.NewLocalStore(coverDir, models.CoverURLPrefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	coverService := covers.NewService(coverStore)
+	coverHandler := handlers.NewCoverHandler(coverService, bookRepo, pendingEditRepo)
+	go covers.NewRemoteImporter(coverService, bookRepo).Run(time.Hour)
+	moderationHandler := handlers.NewModerationHandler(pendingEditRepo, bookRepo, userRepo, duplicateRepo, coverService)
+	middleware.SetRoleLookup(userRepo.GetRole)
+	requireLibrarian := middleware.RequireRole(models.RoleLibrarian)
+	requireModerator := middleware.RequireRole(models.RoleModerator)
+	requireAdmin := middleware.RequireRole(models.RoleAdmin)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", healthHandler)
@@ -69,7 +118,10 @@ func main() {
 	mux.HandleFunc("/api/auth/google/finalize", middleware.RateLimit(authHandler.FinaliseOAuth))
 	mux.HandleFunc("/api/profile", middleware.AuthMiddleware(profileHandler))
 	mux.HandleFunc("/api/books", func(w http.ResponseWriter, r *http.Request) {
-    if r.Method == http.MethodGet {
+    if r.Method == http.MethodGet && r.URL.Query().Get("exclude_shelved") == "true" {
+        // Depends on the viewer's shelves, so it bypasses the shared list cache
+        middleware.AuthMiddleware(bookHandler.List)(w, r)
+    } else if r.Method == http.MethodGet {
         cache.CacheMiddleware(cache.TTLBooksList)(bookHandler.List)(w, r)
     } else if r.Method == http.MethodPost {
         middleware.AuthMiddleware(bookHandler.Create)(w, r)
@@ -78,7 +130,9 @@ func main() {
     }
 	})
 	mux.HandleFunc("/api/books/", func(w http.ResponseWriter, r *http.Request) {
-    if r.Method == http.MethodGet {
+    if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/books/isbn/") {
+        bookHandler.GetByISBN(w, r)
+    } else if r.Method == http.MethodGet {
         cache.CacheMiddleware(cache.TTLBookDetails)(bookHandler.Get)(w, r)
     } else if r.Method == http.MethodPut || r.Method == http.MethodPatch {
         middleware.AuthMiddleware(bookHandler.Update)(w, r)
@@ -88,6 +142,27 @@ func main() {
         http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
     }
 	})
+	mux.HandleFunc("/api/books/{id}/cover", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			middleware.AuthMiddleware(coverHandler.Upload)(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/books/{id}/history", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			bookHandler.GetHistory(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/books/{id}/history/{revisionID}/revert", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			middleware.AuthMiddleware(requireLibrarian(bookHandler.Revert))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
 	mux.HandleFunc("/api/books/{id}/ratings/me", func(w http.ResponseWriter, r *http.Request) {
 		bookID := r.PathValue("id")
 		query := r.URL.Query()
@@ -99,6 +174,13 @@ func main() {
 			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		}
 	})
+	mux.HandleFunc("/api/books/{id}/ratings/histogram", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			ratingHandler.GetHistogram(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
 	mux.HandleFunc("/api/books/{id}/ratings", func(w http.ResponseWriter, r *http.Request) {
 		bookID := r.PathValue("id")
 		query := r.URL.Query()
@@ -115,6 +197,43 @@ func main() {
 			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		}
 	})
+	mux.HandleFunc("/api/books/{id}/sessions", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			middleware.AuthMiddleware(sessionHandler.ListForBook)(w, r)
+		case http.MethodPost:
+			middleware.AuthMiddleware(sessionHandler.Create)(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut, http.MethodPatch:
+			middleware.AuthMiddleware(sessionHandler.Update)(w, r)
+		case http.MethodDelete:
+			middleware.AuthMiddleware(sessionHandler.Delete)(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/books/{id}/progress", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			middleware.AuthMiddleware(progressHandler.ListForBook)(w, r)
+		case http.MethodPost:
+			middleware.AuthMiddleware(progressHandler.Create)(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/progress/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			middleware.AuthMiddleware(progressHandler.Delete)(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
 	mux.HandleFunc("/api/users/me/ratings", func(w http.ResponseWriter, r *http.Request) {
     if r.Method == http.MethodGet {
         middleware.AuthMiddleware(cache.CacheMiddleware(cache.TTLUserRatings)(ratingHandler.GetMyRatings))(w, r)
@@ -133,6 +252,16 @@ func main() {
 		}
 	})
 	mux.HandleFunc("/api/users/{id}/profile", userHandler.GetProfile)
+	mux.HandleFunc("/api/users/me/preferences", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			middleware.AuthMiddleware(userHandler.GetPreferences)(w, r)
+		case http.MethodPut, http.MethodPatch:
+			middleware.AuthMiddleware(userHandler.UpdatePreferences)(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
 	mux.HandleFunc("/api/users/{id}/follow", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
@@ -143,6 +272,13 @@ func main() {
 			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		}
 	})
+	mux.HandleFunc("/api/ratings/{id}/revisions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			middleware.OptionalAuthMiddleware(ratingHandler.GetRevisions)(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
 	mux.HandleFunc("/api/ratings/{id}/comments", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
@@ -169,7 +305,7 @@ func main() {
 	})
 	mux.HandleFunc("/api/users/{id}/followers", userHandler.GetFollowers)
 	mux.HandleFunc("/api/users/{id}/following", userHandler.GetFollowing)
-	mux.HandleFunc("/api/feed", cache.CacheMiddleware(cache.TTLUserFeed)(middleware.OptionalAuthMiddleware(feedHandler.GetFeed)))
+	mux.HandleFunc("/api/feed", middleware.OptionalAuthMiddleware(cache.CacheMiddleware(cache.TTLUserFeed)(feedHandler.GetFeed)))
 	mux.HandleFunc("/api/books/trending", cache.CacheMiddleware(cache.TTLTrending)(bookHandler.GetTrending))
 	mux.HandleFunc("/api/books/popular", cache.CacheMiddleware(cache.TTLPopular)(bookHandler.GetPopular))
 	mux.HandleFunc("/api/books/{id}/similar", bookHandler.GetSimilar)
@@ -222,11 +358,187 @@ func main() {
 	mux.HandleFunc("/api/users/me/bookmarked-lists", middleware.AuthMiddleware(listHandler.GetBookmarkedLists))
 	mux.HandleFunc("/api/lists/popular", cache.CacheMiddleware(cache.TTLPopular)(listHandler.GetPopularLists))
 	mux.HandleFunc("/api/users/{id}/lists", cache.CacheMiddleware(cache.TTLUserProfile)(listHandler.GetUserLists))
-	mux.HandleFunc("/api/users/{id}/stats/year/{year}", userHandler.GetYearStats)
-	mux.HandleFunc("/api/genres", cache.CacheMiddleware(cache.TTLGenres)(genreHandler.GetAll))
+	mux.HandleFunc("/api/shelves", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			middleware.AuthMiddleware(shelfHandler.Create)(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/shelves/{id}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			middleware.OptionalAuthMiddleware(shelfHandler.GetByID)(w, r)
+		case http.MethodPut, http.MethodPatch:
+			middleware.AuthMiddleware(shelfHandler.Update)(w, r)
+		case http.MethodDelete:
+			middleware.AuthMiddleware(shelfHandler.Delete)(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/shelves/{id}/books", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			middleware.OptionalAuthMiddleware(shelfHandler.GetBooks)(w, r)
+		case http.MethodPost:
+			middleware.AuthMiddleware(shelfHandler.AddBook)(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/shelves/{id}/books/{bookID}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			middleware.AuthMiddleware(shelfHandler.RemoveBook)(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/users/{id}/shelves", middleware.OptionalAuthMiddleware(shelfHandler.GetUserShelves))
+	mux.HandleFunc("/api/users/{id}/stats/year/{year}", middleware.OptionalAuthMiddleware(userHandler.GetYearStats))
+	mux.HandleFunc("/api/authors/{id}", middleware.OptionalAuthMiddleware(cache.CacheMiddleware(cache.TTLAuthor)(authorHandler.Get)))
+	mux.HandleFunc("/api/authors/{id}/books", middleware.OptionalAuthMiddleware(cache.CacheMiddleware(cache.TTLAuthor)(authorHandler.GetBooks)))
+	mux.HandleFunc("/api/series", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			middleware.AuthMiddleware(requireLibrarian(seriesHandler.Create))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/series/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			middleware.OptionalAuthMiddleware(seriesHandler.GetByID)(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/series/{id}/books", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			middleware.AuthMiddleware(requireLibrarian(seriesHandler.AddBook))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/series/{id}/books/{bookID}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			middleware.AuthMiddleware(requireLibrarian(seriesHandler.RemoveBook))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/moderation/edits", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			middleware.AuthMiddleware(requireModerator(moderationHandler.ListEdits))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/moderation/edits/{id}/approve", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			middleware.AuthMiddleware(requireModerator(moderationHandler.ApproveEdit))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/moderation/edits/{id}/reject", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			middleware.AuthMiddleware(requireModerator(moderationHandler.RejectEdit))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/books/{id}/duplicates", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			middleware.AuthMiddleware(requireModerator(moderationHandler.GetDuplicateCandidates))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/admin/duplicates", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			middleware.AuthMiddleware(requireModerator(moderationHandler.ListDuplicateGroups))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/admin/books/merge", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			middleware.AuthMiddleware(requireAdmin(moderationHandler.MergeBooks))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/admin/users/{id}/role", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			middleware.AuthMiddleware(requireAdmin(moderationHandler.SetUserRole))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/search/suggest", cache.CacheMiddleware(cache.TTLSuggest)(searchHandler.Suggest))
+	mux.HandleFunc("/api/genres", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			cache.CacheMiddleware(cache.TTLGenres)(genreHandler.GetAll)(w, r)
+		case http.MethodPost:
+			middleware.AuthMiddleware(requireLibrarian(genreHandler.Create))(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/genres/{id}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut, http.MethodPatch:
+			middleware.AuthMiddleware(requireLibrarian(genreHandler.Update))(w, r)
+		case http.MethodDelete:
+			middleware.AuthMiddleware(requireLibrarian(genreHandler.Delete))(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/genres/{id}/merge", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			middleware.AuthMiddleware(requireLibrarian(genreHandler.Merge))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/tags", cache.CacheMiddleware(cache.TTLTags)(tagHandler.GetPopular))
+	mux.HandleFunc("/api/books/{id}/tags", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			middleware.OptionalAuthMiddleware(tagHandler.GetBookTags)(w, r)
+		case http.MethodPost:
+			middleware.AuthMiddleware(tagHandler.Vote)(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/books/{id}/tags/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			middleware.AuthMiddleware(tagHandler.Unvote)(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/books/{id}/genres", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			middleware.AuthMiddleware(requireLibrarian(genreHandler.AssignToBook))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/books/{id}/genres/{genreID}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			middleware.AuthMiddleware(requireLibrarian(genreHandler.UnassignFromBook))(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
 	mux.HandleFunc("/api/embed/users/{id}/books", embedHandler.GetUserBooks)
 	mux.HandleFunc("/api/embed/lists/{id}", embedHandler.GetListBooks)
 	mux.HandleFunc("/api/import/goodreads", middleware.AuthMiddleware(importHandler.ImportGoodreads))
+	mux.HandleFunc(models.CoverURLPrefix, coverHandler.Serve)
 	fs := http.FileServer(http.Dir("./static"))
 	mux.Handle("/", fs)
 
@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"regexp"
 	"strings"
 	"time"
 
@@ -1001,7 +1000,7 @@ func fetchAndInsert(repo *database.BookRepository, genreRepo *database.GenreRepo
 			}
 
 			// Dedup: normalized title+author
-			bookKey := normalizeBookKey(book.VolumeInfo.Title, author)
+			bookKey := models.NormalizeBookKey(book.VolumeInfo.Title, author)
 			if _, exists := insertedTitles[bookKey]; exists {
 				skipped++
 				continue
@@ -1010,15 +1009,17 @@ func fetchAndInsert(repo *database.BookRepository, genreRepo *database.GenreRepo
 			req := models.CreateBookRequest{
 				Title:         book.VolumeInfo.Title,
 				Author:        author,
+				Authors:       authorCredits(book.VolumeInfo.Authors),
 				ISBN:          isbn,
 				Description:   truncateDescription(book.VolumeInfo.Description),
 				PublishedYear: year,
 				CoverURL:      getCoverURL(book.VolumeInfo.ImageLinks),
+				PageCount:     book.VolumeInfo.PageCount,
 			}
 
 			createdBook, err := repo.Create(req)
 			if err != nil {
-				if strings.Contains(err.Error(), "duplicate key") {
+				if strings.Contains(err.Error(), "duplicate key") || err == models.ErrISBNExists {
 					if isbn != "" {
 						insertedISBNs[isbn] = true
 					}
@@ -1230,49 +1231,22 @@ func loadExistingBooks(db *sql.DB) {
 		var id int
 		var title, author string
 		if err := rows2.Scan(&id, &title, &author); err == nil {
-			key := normalizeBookKey(title, author)
+			key := models.NormalizeBookKey(title, author)
 			insertedTitles[key] = id
 		}
 	}
 }
 
-func normalizeBookKey(title, author string) string {
-	title = strings.ToLower(strings.TrimSpace(title))
-
-	// Remove leading articles
-	title = regexp.MustCompile(`^(the|a|an)\s+`).ReplaceAllString(title, "")
-
-	// Remove subtitle (after : or " - ")
-	if idx := strings.Index(title, ":"); idx > 0 {
-		title = title[:idx]
-	}
-	if idx := strings.Index(title, " - "); idx > 0 {
-		title = title[:idx]
-	}
-
-	// Remove punctuation and collapse whitespace
-	title = regexp.MustCompile(`[^\w\s]`).ReplaceAllString(title, "")
-	title = regexp.MustCompile(`\s+`).ReplaceAllString(title, " ")
-	title = strings.TrimSpace(title)
-
-	// Normalize author
-	author = strings.ToLower(strings.TrimSpace(author))
-	author = regexp.MustCompile(`\s+`).ReplaceAllString(author, " ")
-
-	return title + "|" + author
-}
-
 func extractISBN13(identifiers []IndustryIdentifier) string {
-	// Prefer ISBN-13
-	for _, id := range identifiers {
-		if id.Type == "ISBN_13" {
-			return id.Identifier
-		}
-	}
-	// Fall back to ISBN-10
-	for _, id := range identifiers {
-		if id.Type == "ISBN_10" {
-			return id.Identifier
+	// Prefer ISBN-13, fall back to ISBN-10 converted to ISBN-13
+	for _, idType := range []string{"ISBN_13", "ISBN_10"} {
+		for _, id := range identifiers {
+			if id.Type != idType {
+				continue
+			}
+			if isbn, err := models.NormalizeISBN(id.Identifier); err == nil && isbn != "" {
+				return isbn
+			}
 		}
 	}
 	return ""
@@ -1294,6 +1268,15 @@ func getFirstAuthor(authors []string) string {
 	return "Unknown Author"
 }
 
+// authorCredits keeps every author Google Books lists, not just the first.
+func authorCredits(authors []string) []models.AuthorCredit {
+	credits := []models.AuthorCredit{}
+	for _, name := range authors {
+		credits = append(credits, models.AuthorCredit{Name: name})
+	}
+	return credits
+}
+
 func truncateDescription(desc string) string {
 	if len(desc) > 1000 {
 		return desc[:997] + "..."
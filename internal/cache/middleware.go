@@ -75,6 +75,12 @@ func InvalidateBookCache(bookID string) error {
 	return nil
 }
 
+// InvalidateFeedCache drops every cached feed page, for changes such as an
+// entry's visibility that affect what other users see.
+func InvalidateFeedCache() error {
+	return DeletePattern("cache:*/api/feed*")
+}
+
 func InvalidateBookCacheByID(bookID int) error {
 	return InvalidateBookCache(strconv.Itoa(bookID))
 }
\ No newline at end of file
@@ -26,6 +26,9 @@ const (
 	TTLUserProfile = 30 * time.Minute
 	TTLUserRatings = 30 * time.Minute
 	TTLBooksList   = 1 * time.Hour
+	TTLSuggest     = 10 * time.Minute
+	TTLAuthor      = 1 * time.Hour
+	TTLTags        = 1 * time.Hour
 )
 
 func InitRedis() error {
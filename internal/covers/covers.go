@@ -0,0 +1,117 @@
+package covers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"strings"
+
+	"github.com/pulkyeet/BookmarkD/internal/models"
+	"github.com/pulkyeet/BookmarkD/internal/storage"
+)
+
+const (
+	MaxUploadBytes = 5 << 20
+	// maxPixels guards against decompression bombs that are small on the wire
+	maxPixels   = 40_000_000
+	jpegQuality = 85
+)
+
+var ErrUnsupportedImage = errors.New("unsupported or invalid image")
+
+type Service struct {
+	store storage.BlobStore
+}
+
+func NewService(store storage.BlobStore) *Service {
+	return &Service{store: store}
+}
+
+func (s *Service) Store() storage.BlobStore {
+	return s.store
+}
+
+// Save decodes an uploaded image, writes every thumbnail size to the blob
+// store and returns the public URL of the default size. Keys are derived from
+// the content hash, so saving the same image twice is a no-op.
+func (s *Service) Save(data []byte) (string, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return "", ErrUnsupportedImage
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:16])
+	defaultKey := models.CoverKey(hash, models.CoverDefaultSize)
+	if exists, err := s.store.Exists(defaultKey); err == nil && exists {
+		return s.store.URL(defaultKey), nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrUnsupportedImage
+	}
+	img = flatten(img)
+
+	// Write the default size last so its existence means the set is complete
+	defaultWidth := 0
+	for _, size := range models.CoverSizes {
+		if size.Name == models.CoverDefaultSize {
+			defaultWidth = size.Width
+			continue
+		}
+		if err := s.put(models.CoverKey(hash, size.Name), resize(img, size.Width)); err != nil {
+			return "", err
+		}
+	}
+	if err := s.put(defaultKey, resize(img, defaultWidth)); err != nil {
+		return "", err
+	}
+	return s.store.URL(defaultKey), nil
+}
+
+// Discard removes every thumbnail of a locally stored cover. Covers are shared
+// by content, so callers must check that nothing else uses coverURL first.
+func (s *Service) Discard(coverURL string) error {
+	thumbnails := models.CoverThumbnails(coverURL)
+	if thumbnails == nil {
+		return nil
+	}
+	// Remove the default size first so a half-deleted set isn't taken as complete
+	keys := []string{strings.TrimPrefix(thumbnails[models.CoverDefaultSize], models.CoverURLPrefix)}
+	for name, url := range thumbnails {
+		if name != models.CoverDefaultSize {
+			keys = append(keys, strings.TrimPrefix(url, models.CoverURLPrefix))
+		}
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) put(key string, img image.Image) error {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return err
+	}
+	return s.store.Put(key, buf.Bytes(), "image/jpeg")
+}
+
+// flatten draws transparent images onto white, since thumbnails are JPEG.
+func flatten(img image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,189 @@
+package covers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pulkyeet/BookmarkD/internal/cache"
+	"github.com/pulkyeet/BookmarkD/internal/database"
+)
+
+const (
+	importBatchSize = 100
+	// importDelay spaces out requests so we don't hammer the upstream host
+	importDelay  = 250 * time.Millisecond
+	maxRedirects = 5
+)
+
+var errBlockedAddress = errors.New("cover host resolves to a private address")
+
+// RemoteImporter copies hotlinked cover_urls, mostly Google Books links from
+// the seeder, into the blob store so covers survive upstream changes.
+type RemoteImporter struct {
+	service  *Service
+	bookRepo *database.BookRepository
+	client   *http.Client
+}
+
+func NewRemoteImporter(service *Service, bookRepo *database.BookRepository) *RemoteImporter {
+	return &RemoteImporter{
+		service:  service,
+		bookRepo: bookRepo,
+		client:   newPublicClient(15 * time.Second),
+	}
+}
+
+// newPublicClient returns a client that only talks to public hosts over http
+// and https. cover_url is user input, so the address is checked when each
+// connection is dialled, which covers redirects and DNS answers that change
+// between lookup and connect.
+func newPublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isPublicIP(ip) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return checkCoverURL(req.URL)
+		},
+	}
+}
+
+// checkCoverURL allows only http and https URLs with a host.
+func checkCoverURL(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("unsupported cover URL %q", u.Redacted())
+	}
+	return nil
+}
+
+// isPublicIP reports whether ip is a globally routable unicast address, so not
+// loopback, private, link-local (including cloud metadata at 169.254.169.254),
+// multicast or unspecified.
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	// Carrier-grade NAT, which some hosts use for internal networks
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 100 && ip4[1]&0xc0 == 64 {
+		return false
+	}
+	return true
+}
+
+// Run imports a batch every interval until the process exits. It is meant to
+// be started in its own goroutine. When several API instances run, only the
+// one holding the importer lock does the work.
+func (i *RemoteImporter) Run(interval time.Duration) {
+	for {
+		i.runLocked()
+		time.Sleep(interval)
+	}
+}
+
+func (i *RemoteImporter) runLocked() {
+	release, ok, err := i.bookRepo.TryCoverImportLock()
+	if err != nil {
+		log.Printf("Cover import lock error: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	defer release()
+	imported, failed, err := i.RunOnce()
+	if err != nil {
+		log.Printf("Cover import error: %v", err)
+	} else if imported > 0 || failed > 0 {
+		log.Printf("Cover import: %d imported, %d failed", imported, failed)
+	}
+}
+
+// RunOnce imports one batch of remote covers.
+func (i *RemoteImporter) RunOnce() (imported, failed int, err error) {
+	remote, err := i.bookRepo.ListRemoteCovers(importBatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, cover := range remote {
+		localURL, err := i.fetch(cover.URL)
+		if err != nil {
+			log.Printf("Cover import for book %d failed: %v", cover.BookID, err)
+			if err := i.bookRepo.MarkCoverFetchFailed(cover.BookID); err != nil {
+				return imported, failed, err
+			}
+			failed++
+			time.Sleep(importDelay)
+			continue
+		}
+		replaced, err := i.bookRepo.ReplaceRemoteCover(cover.BookID, cover.URL, localURL)
+		if err != nil {
+			return imported, failed, err
+		}
+		if replaced {
+			cache.InvalidateBookCache(strconv.Itoa(cover.BookID))
+			imported++
+		}
+		time.Sleep(importDelay)
+	}
+	return imported, failed, nil
+}
+
+func (i *RemoteImporter) fetch(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if err := checkCoverURL(u); err != nil {
+		return "", err
+	}
+	resp, err := i.client.Get(u.String())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "image/") {
+		return "", fmt.Errorf("unexpected content type %q", ct)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxUploadBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > MaxUploadBytes {
+		return "", fmt.Errorf("cover larger than %d bytes", MaxUploadBytes)
+	}
+	return i.service.Save(data)
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+type AuthorRepository struct {
+	db *sql.DB
+}
+
+func NewAuthorRepository(db *sql.DB) *AuthorRepository {
+	return &AuthorRepository{db: db}
+}
+
+func (r *AuthorRepository) GetByID(id int) (*models.AuthorDetail, error) {
+	query := `SELECT a.id, a.name, a.created_at, COUNT(DISTINCT ba.book_id)
+FROM authors a
+LEFT JOIN book_authors ba ON a.id = ba.author_id
+WHERE a.id = $1
+GROUP BY a.id, a.name, a.created_at`
+	author := &models.AuthorDetail{}
+	err := r.db.QueryRow(query, id).Scan(&author.ID, &author.Name, &author.CreatedAt, &author.BookCount)
+	if err == sql.ErrNoRows {
+		return nil, sql.ErrNoRows
+	}
+	if err != nil {
+		return nil, err
+	}
+	return author, nil
+}
+
+// GetBooks lists an author's bibliography. sortBy is "year" (oldest first), "year_desc" or "popularity".
+func (r *AuthorRepository) GetBooks(authorID int, sortBy string, limit, offset int) ([]models.AuthorBook, error) {
+	query := `SELECT b.id, b.title, b.cover_url, b.published_year, MIN(ba.role::text) AS role,
+	COALESCE(AVG(r.rating) FILTER (WHERE r.rating > 0), 0) AS avg_rating,
+	COUNT(r.id) FILTER (WHERE r.rating > 0) AS rating_count
+FROM book_authors ba
+JOIN books b ON ba.book_id = b.id
+LEFT JOIN ratings r ON b.id = r.book_id
+WHERE ba.author_id = $1
+GROUP BY b.id, b.title, b.cover_url, b.published_year`
+
+	switch sortBy {
+	case "popularity":
+		query += ` ORDER BY rating_count DESC, avg_rating DESC, b.id`
+	case "year_desc":
+		query += ` ORDER BY b.published_year DESC NULLS LAST, b.id`
+	case "year":
+		fallthrough
+	default:
+		query += ` ORDER BY b.published_year ASC NULLS LAST, b.id`
+	}
+	query += ` LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, authorID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []models.AuthorBook{}
+	for rows.Next() {
+		var book models.AuthorBook
+		var coverNull sql.NullString
+		var yearNull sql.NullInt64
+		err := rows.Scan(&book.BookID, &book.Title, &coverNull, &yearNull, &book.Role, &book.AverageRating, &book.RatingCount)
+		if err != nil {
+			return nil, err
+		}
+		book.CoverURL = coverNull.String
+		book.PublishedYear = int(yearNull.Int64)
+		books = append(books, book)
+	}
+	return books, nil
+}
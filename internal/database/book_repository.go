@@ -1,13 +1,33 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"github.com/lib/pq"
 	"github.com/pulkyeet/BookmarkD/internal/models"
 	"strings"
 )
 
+// searchQueryExpr turns free text into a tsquery; websearch syntax supports quotes, OR and -exclusions.
+const searchQueryExpr = "websearch_to_tsquery('english', $%d)"
+
+const (
+	titleHeadlineOptions       = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+	descriptionHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10"
+)
+
+// genreWithDescendants selects the ids of the genres named in the lowercased array placeholder
+// and all of their subgenres.
+const genreWithDescendants = `
+	WITH RECURSIVE tree AS (
+		SELECT id FROM genres WHERE LOWER(name) = ANY($%d)
+		UNION
+		SELECT g.id FROM genres g JOIN tree t ON g.parent_id = t.id
+	)
+	SELECT id FROM tree`
+
 type BookRepository struct {
 	db *sql.DB
 }
@@ -17,23 +37,48 @@ func NewBookRepository(db *sql.DB) *BookRepository {
 }
 
 func (r *BookRepository) Create(req models.CreateBookRequest) (*models.Book, error) {
+	credits := models.NormaliseAuthorCredits(req.Authors, req.Author)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// A book without an explicit work is the first edition of a new work
+	workID := req.WorkID
+	if workID != 0 {
+		if err := workExists(tx, workID); err != nil {
+			return nil, err
+		}
+	} else {
+		err = tx.QueryRow(`INSERT INTO works (title) VALUES ($1) RETURNING id`, req.Title).Scan(&workID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	query := `
-		INSERT INTO books (title, author, isbn, description, published_year, cover_url)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, title, author, isbn, description, published_year, cover_url, created_at, updated_at
+		INSERT INTO books (title, author, isbn, description, published_year, cover_url, work_id, format, publisher, page_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, title, author, isbn, description, published_year, cover_url, work_id, format, publisher, page_count, created_at, updated_at
 		`
 
 	book := &models.Book{}
-	var isbn, description, coverURL sql.NullString
-	var publishedYear sql.NullInt64
-	err := r.db.QueryRow(
+	var isbn, description, coverURL, format, publisher sql.NullString
+	var publishedYear, pageCount sql.NullInt64
+	err = tx.QueryRow(
 		query,
 		req.Title,
-		req.Author,
+		models.AuthorDisplayName(credits),
 		nullString(req.ISBN),
 		nullString(req.Description),
 		nullInt(req.PublishedYear),
 		nullString(req.CoverURL),
+		workID,
+		nullString(req.Format),
+		nullString(req.Publisher),
+		nullInt(req.PageCount),
 	).Scan(
 		&book.ID,
 		&book.Title,
@@ -42,9 +87,16 @@ func (r *BookRepository) Create(req models.CreateBookRequest) (*models.Book, err
 		&description,
 		&publishedYear,
 		&coverURL,
+		&book.WorkID,
+		&format,
+		&publisher,
+		&pageCount,
 		&book.CreatedAt,
 		&book.UpdatedAt,
 	)
+	if isISBNConflict(err) {
+		return nil, models.ErrISBNExists
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -52,18 +104,29 @@ func (r *BookRepository) Create(req models.CreateBookRequest) (*models.Book, err
 	book.Description = description.String
 	book.PublishedYear = int(publishedYear.Int64)
 	book.CoverURL = coverURL.String
+	book.Format = format.String
+	book.Publisher = publisher.String
+	book.PageCount = int(pageCount.Int64)
+
+	book.Authors, err = setBookAuthors(tx, book.ID, credits)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return book, nil
 }
 
 func (r *BookRepository) GetByID(id int) (*models.Book, error) {
 	query := `
-		SELECT id, title, author, isbn, description, published_year, cover_url, created_at, updated_at
+		SELECT id, title, author, isbn, description, published_year, cover_url, work_id, format, publisher, page_count, created_at, updated_at
 		FROM books
 		WHERE id = $1`
 
 	book := &models.Book{}
-	var isbn, description, coverURL sql.NullString
-	var publishedYear sql.NullInt64
+	var isbn, description, coverURL, format, publisher sql.NullString
+	var publishedYear, pageCount sql.NullInt64
 	err := r.db.QueryRow(query, id).Scan(
 		&book.ID,
 		&book.Title,
@@ -72,6 +135,10 @@ func (r *BookRepository) GetByID(id int) (*models.Book, error) {
 		&description,
 		&publishedYear,
 		&coverURL,
+		&book.WorkID,
+		&format,
+		&publisher,
+		&pageCount,
 		&book.CreatedAt,
 		&book.UpdatedAt,
 	)
@@ -85,25 +152,34 @@ func (r *BookRepository) GetByID(id int) (*models.Book, error) {
 	book.Description = description.String
 	book.PublishedYear = int(publishedYear.Int64)
 	book.CoverURL = coverURL.String
+	book.Format = format.String
+	book.Publisher = publisher.String
+	book.PageCount = int(pageCount.Int64)
+	if err := r.attachBookDetails([]*models.Book{book}); err != nil {
+		return nil, err
+	}
 	return book, nil
 }
 
 func (r *BookRepository) List(limit, offset int, search string) ([]*models.Book, error) {
 	query := `
-		SELECT id, title, author, isbn, description, published_year, cover_url, created_at, updated_at
+		SELECT id, title, author, isbn, description, published_year, cover_url, work_id, format, publisher, page_count, created_at, updated_at
 		FROM books
 		WHERE 1=1
 	`
 	args := []interface{}{}
 	argCount := 1
 
+	orderBy := " ORDER BY created_at DESC"
 	if search != "" {
-		query += fmt.Sprintf(" AND (title ILIKE $%d OR author ILIKE $%d)", argCount, argCount)
-		args = append(args, "%"+search+"%")
+		tsQuery := fmt.Sprintf(searchQueryExpr, argCount)
+		query += fmt.Sprintf(" AND search_vector @@ %s", tsQuery)
+		orderBy = fmt.Sprintf(" ORDER BY ts_rank(search_vector, %s) DESC, created_at DESC", tsQuery)
+		args = append(args, search)
 		argCount++
 	}
 
-	query += " ORDER BY created_at DESC"
+	query += orderBy
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argCount, argCount+1)
 	args = append(args, limit, offset)
 
@@ -116,8 +192,8 @@ func (r *BookRepository) List(limit, offset int, search string) ([]*models.Book,
 	books := []*models.Book{}
 	for rows.Next() {
 		book := &models.Book{}
-		var isbn, description, coverURL sql.NullString
-		var publishedYear sql.NullInt64
+		var isbn, description, coverURL, format, publisher sql.NullString
+		var publishedYear, pageCount sql.NullInt64
 
 		err := rows.Scan(
 			&book.ID,
@@ -127,6 +203,10 @@ func (r *BookRepository) List(limit, offset int, search string) ([]*models.Book,
 			&description,
 			&publishedYear,
 			&coverURL,
+			&book.WorkID,
+			&format,
+			&publisher,
+			&pageCount,
 			&book.CreatedAt,
 			&book.UpdatedAt,
 		)
@@ -139,14 +219,25 @@ func (r *BookRepository) List(limit, offset int, search string) ([]*models.Book,
 		book.Description = description.String
 		book.PublishedYear = int(publishedYear.Int64)
 		book.CoverURL = coverURL.String
+		book.Format = format.String
+		book.Publisher = publisher.String
+		book.PageCount = int(pageCount.Int64)
 
 		books = append(books, book)
 	}
 
+	if err := r.attachBookDetails(books); err != nil {
+		return nil, err
+	}
 	return books, nil
 }
 
-func (r *BookRepository) Update(id int, req models.UpdateBookRequest) (*models.Book, error) {
+// Update applies a partial edit and records the changed fields as a revision by editorID.
+func (r *BookRepository) Update(id int, req models.UpdateBookRequest, editorID int) (*models.Book, error) {
+	return r.update(id, req, editorID, nil)
+}
+
+func (r *BookRepository) update(id int, req models.UpdateBookRequest, editorID int, revertedFrom *int) (*models.Book, error) {
 	// Building dynamic update query
 	updates := []string{}
 	args := []interface{}{}
@@ -157,29 +248,59 @@ func (r *BookRepository) Update(id int, req models.UpdateBookRequest) (*models.B
 		args = append(args, *req.Title)
 		argCount++
 	}
-	if req.Author != nil {
+	var credits []models.AuthorCredit
+	if req.Authors != nil || req.Author != nil {
+		var legacy string
+		var structured []models.AuthorCredit
+		if req.Author != nil {
+			legacy = *req.Author
+		}
+		if req.Authors != nil {
+			structured = *req.Authors
+		}
+		credits = models.NormaliseAuthorCredits(structured, legacy)
 		updates = append(updates, fmt.Sprintf("author = $%d", argCount))
-		args = append(args, *req.Author)
+		args = append(args, models.AuthorDisplayName(credits))
 		argCount++
 	}
 	if req.ISBN != nil {
 		updates = append(updates, fmt.Sprintf("isbn = $%d", argCount))
-		args = append(args, *req.ISBN)
+		args = append(args, nullString(*req.ISBN))
 		argCount++
 	}
 	if req.Description != nil {
 		updates = append(updates, fmt.Sprintf("description = $%d", argCount))
-		args = append(args, *req.Description)
+		args = append(args, nullString(*req.Description))
 		argCount++
 	}
 	if req.PublishedYear != nil {
 		updates = append(updates, fmt.Sprintf("published_year = $%d", argCount))
-		args = append(args, *req.PublishedYear)
+		args = append(args, nullInt(*req.PublishedYear))
 		argCount++
 	}
 	if req.CoverURL != nil {
 		updates = append(updates, fmt.Sprintf("cover_url = $%d", argCount))
-		args = append(args, *req.CoverURL)
+		args = append(args, nullString(*req.CoverURL))
+		argCount++
+	}
+	if req.WorkID != nil {
+		updates = append(updates, fmt.Sprintf("work_id = $%d", argCount))
+		args = append(args, *req.WorkID)
+		argCount++
+	}
+	if req.Format != nil {
+		updates = append(updates, fmt.Sprintf("format = $%d", argCount))
+		args = append(args, nullString(*req.Format))
+		argCount++
+	}
+	if req.Publisher != nil {
+		updates = append(updates, fmt.Sprintf("publisher = $%d", argCount))
+		args = append(args, nullString(*req.Publisher))
+		argCount++
+	}
+	if req.PageCount != nil {
+		updates = append(updates, fmt.Sprintf("page_count = $%d", argCount))
+		args = append(args, nullInt(*req.PageCount))
 		argCount++
 	}
 	if len(updates) == 0 {
@@ -188,72 +309,295 @@ func (r *BookRepository) Update(id int, req models.UpdateBookRequest) (*models.B
 	updates = append(updates, "updated_at = CURRENT_TIMESTAMP")
 	args = append(args, id)
 
-	query := fmt.Sprintf(`
-		UPDATE books
-		SET %s
-		WHERE id = $%d
-		RETURNING id, title, author, isbn, description, published_year, cover_url, created_at, updated_at
-		`, strings.Join(updates, ", "), argCount)
+	query := fmt.Sprintf(`UPDATE books SET %s WHERE id = $%d`, strings.Join(updates, ", "), argCount)
 
-	book := &models.Book{}
-	err := r.db.QueryRow(query, args...).Scan(
-		&book.ID,
-		&book.Title,
-		&book.Author,
-		&book.ISBN,
-		&book.Description,
-		&book.PublishedYear,
-		&book.CoverURL,
-		&book.CreatedAt,
-		&book.UpdatedAt,
-	)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockBookForUpdate(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := models.DiffBookUpdate(current, req, credits)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.WorkID != nil {
+		if err := workExists(tx, *req.WorkID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		if isISBNConflict(err) {
+			return nil, models.ErrISBNExists
+		}
+		return nil, err
+	}
+	if len(changes) > 0 {
+		changesJSON, err := json.Marshal(changes)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(`INSERT INTO book_revisions (book_id, user_id, changes, reverted_from) VALUES ($1, $2, $3, $4)`,
+			id, editorID, changesJSON, revertedFrom)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if credits != nil {
+		if _, err := setBookAuthors(tx, id, credits); err != nil {
+			return nil, err
+		}
+	}
+	if req.WorkID != nil {
+		// Moving the last edition out of a work leaves it empty
+		_, err := tx.Exec(`DELETE FROM works w WHERE NOT EXISTS (SELECT 1 FROM books b WHERE b.work_id = w.id)`)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(id)
+}
+
+func (r *BookRepository) GetRevisions(bookID, limit, offset int) ([]*models.BookRevision, error) {
+	query := bookRevisionSelect + `
+	WHERE br.book_id = $1
+	ORDER BY br.created_at DESC, br.id DESC
+	LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, bookID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*models.BookRevision{}
+	for rows.Next() {
+		revision, err := scanBookRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// An empty page may just be past the end, or the book may not exist
+	if len(revisions) == 0 {
+		var exists bool
+		if err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM books WHERE id = $1)`, bookID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("book not found")
+		}
+	}
+	return revisions, nil
+}
+
+func (r *BookRepository) GetRevision(bookID, revisionID int) (*models.BookRevision, error) {
+	return scanBookRevision(r.db.QueryRow(bookRevisionSelect+` WHERE br.book_id = $1 AND br.id = $2`, bookID, revisionID))
+}
+
+// RevertRevision restores the fields an earlier revision changed to their
+// previous values. The revert is itself recorded as a new revision.
+func (r *BookRepository) RevertRevision(bookID, revisionID, editorID int) (*models.Book, error) {
+	revision, err := r.GetRevision(bookID, revisionID)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("Book not found.")
+		return nil, fmt.Errorf("revision not found")
 	}
 	if err != nil {
 		return nil, err
 	}
-	return book, nil
+	req, err := revision.RevertRequest()
+	if err != nil {
+		return nil, err
+	}
+	return r.update(bookID, req, editorID, &revision.ID)
+}
+
+const bookRevisionSelect = `
+	SELECT br.id, br.book_id, br.user_id, COALESCE(u.username, ''), br.changes, br.reverted_from, br.created_at
+	FROM book_revisions br
+	LEFT JOIN users u ON u.id = br.user_id`
+
+func scanBookRevision(row rowScanner) (*models.BookRevision, error) {
+	revision := &models.BookRevision{}
+	var userID, revertedFrom sql.NullInt64
+	var changes []byte
+	err := row.Scan(&revision.ID, &revision.BookID, &userID, &revision.Username, &changes, &revertedFrom, &revision.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(changes, &revision.Changes); err != nil {
+		return nil, err
+	}
+	if userID.Valid {
+		id := int(userID.Int64)
+		revision.UserID = &id
+	}
+	if revertedFrom.Valid {
+		id := int(revertedFrom.Int64)
+		revision.RevertedFrom = &id
+	}
+	return revision, nil
 }
 
 func (r *BookRepository) Delete(id int) error {
-	query := `DELETE FROM books WHERE id = $1`
-	result, err := r.db.Exec(query, id)
+	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
-	rows, err := result.RowsAffected()
+	defer tx.Rollback()
+
+	var workID int
+	err = tx.QueryRow(`DELETE FROM books WHERE id = $1 RETURNING work_id`, id).Scan(&workID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("book not found")
+	}
 	if err != nil {
 		return err
 	}
-	if rows == 0 {
-		return fmt.Errorf("Book now found.")
+	// Deleting the last edition leaves its work empty
+	_, err = tx.Exec(`DELETE FROM works w WHERE w.id = $1 AND NOT EXISTS (SELECT 1 FROM books b WHERE b.work_id = w.id)`, workID)
+	if err != nil {
+		return err
 	}
-	return nil
+	return tx.Commit()
 }
 
-func (r *BookRepository) ListWithGenres(limit, offset int, search, genreFilter string) ([]*models.BookWithGenres, error) {
-	query := `SELECT DISTINCT b.id, b.title, b.author, b.isbn, b.description, b.published_year, b.cover_url, b.created_at, b.updated_at
-FROM books b LEFT JOIN book_genres bg ON b.id = bg.book_id LEFT JOIN genres g ON bg.genre_id = g.id WHERE 1=1`
+// bookFilterClause builds the WHERE clause for a book listing over books b.
+// A search term, when present, is always bound to $1.
+func bookFilterClause(f models.BookFilter) (string, []interface{}) {
+	where := " WHERE 1=1"
 	args := []interface{}{}
 	argCount := 1
-	if search != "" {
-		query += fmt.Sprintf(" AND (b.title ILIKE $%d OR b.author ILIKE $%d)", argCount, argCount)
-		args = append(args, "%"+search+"%")
+
+	if f.Search != "" {
+		where += " AND b.search_vector @@ " + fmt.Sprintf(searchQueryExpr, argCount)
+		args = append(args, f.Search)
 		argCount++
 	}
-	if genreFilter != "" {
-		query += fmt.Sprintf(" AND LOWER(g.name) = LOWER($%d)", argCount)
-		args = append(args, genreFilter)
+	if len(f.Genres) > 0 {
+		names := make([]string, len(f.Genres))
+		for i, g := range f.Genres {
+			names[i] = strings.ToLower(g)
+		}
+		// A parent genre also matches books filed under any of its subgenres
+		groups := [][]string{names}
+		if f.MatchAllGenres {
+			groups = groups[:0]
+			for _, name := range names {
+				groups = append(groups, []string{name})
+			}
+		}
+		for _, group := range groups {
+			where += fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM book_genres bg
+			WHERE bg.book_id = b.id AND bg.genre_id IN (`+genreWithDescendants+`))`, argCount)
+			args = append(args, pq.Array(group))
+			argCount++
+		}
+	}
+	if len(f.Tags) > 0 {
+		where += fmt.Sprintf(` AND b.work_id IN (
+			SELECT tv.work_id FROM tag_votes tv JOIN tags t ON t.id = tv.tag_id
+			WHERE t.name = ANY($%d)
+			GROUP BY tv.work_id
+			HAVING COUNT(DISTINCT t.id) = $%d)`, argCount, argCount+1)
+		args = append(args, pq.Array(f.Tags), len(f.Tags))
+		argCount += 2
+	}
+	if f.YearFrom != nil {
+		where += fmt.Sprintf(" AND b.published_year >= $%d", argCount)
+		args = append(args, *f.YearFrom)
 		argCount++
 	}
-	query += " ORDER BY b.created_at DESC"
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argCount, argCount+1)
-	args = append(args, limit, offset)
+	if f.YearTo != nil {
+		where += fmt.Sprintf(" AND b.published_year <= $%d", argCount)
+		args = append(args, *f.YearTo)
+		argCount++
+	}
+	if f.MinAverage != nil || f.MinRatingCount > 0 {
+		// Ratings roll up per work, so every edition shares the same average
+		minAverage := 0.0
+		if f.MinAverage != nil {
+			minAverage = *f.MinAverage
+		}
+		where += fmt.Sprintf(` AND b.work_id IN (
+			SELECT e.work_id FROM ratings r JOIN books e ON e.id = r.book_id
+			WHERE r.rating > 0
+			GROUP BY e.work_id
+			HAVING AVG(r.rating) >= $%d AND COUNT(r.id) >= $%d)`, argCount, argCount+1)
+		args = append(args, minAverage, f.MinRatingCount)
+		argCount += 2
+	}
+	if f.ExcludeShelvedBy != nil {
+		where += fmt.Sprintf(` AND NOT EXISTS (
+			SELECT 1 FROM ratings r JOIN books e ON e.id = r.book_id
+			WHERE r.user_id = $%d AND e.work_id = b.work_id)`, argCount)
+		args = append(args, *f.ExcludeShelvedBy)
+		argCount++
+	}
+
+	return where, args
+}
+
+// ListWithGenres lists books matching the filter, ranked by relevance when
+// searching and newest first otherwise. It also returns the cursor for the next page.
+func (r *BookRepository) ListWithGenres(page models.PageParams, filter models.BookFilter) ([]*models.BookWithGenres, string, error) {
+	search := filter.Search
+	where, args := bookFilterClause(filter)
+	argCount := len(args) + 1
+
+	tsQuery := ""
+	// ts_rank returns real, which doesn't survive the round trip through the
+	// cursor's float64 exactly, so the rank is float8 wherever it's compared
+	rankExpr := "0::float8"
+	keyset := []string{"b.created_at", "b.id"}
+	if search != "" {
+		tsQuery = fmt.Sprintf(searchQueryExpr, 1)
+		rankExpr = fmt.Sprintf("ts_rank(b.search_vector, %s)::float8", tsQuery)
+		keyset = append([]string{rankExpr}, keyset...)
+	}
+	if page.After != nil {
+		cond, keysetArgs := keysetCondition(page.After, keyset, true, argCount)
+		where += " AND " + cond
+		args = append(args, keysetArgs...)
+		argCount += len(keysetArgs)
+	}
+
+	orderBy := " ORDER BY created_at DESC, id DESC"
+	if search != "" {
+		orderBy = " ORDER BY rank DESC, created_at DESC, id DESC"
+	}
+
+	query := `SELECT b.id, b.title, b.author, b.isbn, b.description, b.published_year, b.cover_url, b.work_id, b.format, b.publisher, b.page_count, b.created_at, b.updated_at, ` + rankExpr + ` AS rank
+FROM books b` + where + orderBy
+	limitClause, limitArgs := pageLimit(page, argCount)
+	query += limitClause
+	args = append(args, limitArgs...)
+
+	if search != "" {
+		// ts_headline re-parses the whole document, so only run it on the page being returned
+		query = fmt.Sprintf(`SELECT p.*,
+	ts_headline('english', p.title, %s, '%s'),
+	ts_headline('english', COALESCE(p.description, ''), %s, '%s')
+FROM (%s) p
+ORDER BY p.rank DESC, p.created_at DESC, p.id DESC`, tsQuery, titleHeadlineOptions, tsQuery, descriptionHeadlineOptions, query)
+	}
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -261,50 +605,66 @@ FROM books b LEFT JOIN book_genres bg ON b.id = bg.book_id LEFT JOIN genres g ON
 	bookOrder := []int{}
 
 	for rows.Next() {
-		var bookID int
-		book := &models.Book{}
-		var isbn, description, coverURL sql.NullString
-		var publishedYear sql.NullInt64
+		book := &models.BookWithGenres{Genres: []models.Genre{}, Tags: []models.BookTag{}}
+		var isbn, description, coverURL, format, publisher sql.NullString
+		var publishedYear, pageCount sql.NullInt64
+		var titleHighlight, descriptionHighlight string
 
-		err := rows.Scan(
-			&bookID,
+		dest := []interface{}{
+			&book.ID,
 			&book.Title,
 			&book.Author,
 			&isbn,
 			&description,
 			&publishedYear,
 			&coverURL,
+			&book.WorkID,
+			&format,
+			&publisher,
+			&pageCount,
 			&book.CreatedAt,
 			&book.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
+			&book.SearchRank,
+		}
+		if search != "" {
+			dest = append(dest, &titleHighlight, &descriptionHighlight)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, "", err
 		}
 
-		if _, exists := bookMap[bookID]; !exists {
-			book.ID = bookID
-			book.ISBN = isbn.String
-			book.Description = description.String
-			book.PublishedYear = int(publishedYear.Int64)
-			book.CoverURL = coverURL.String
-
-			bookMap[bookID] = &models.BookWithGenres{
-				Book:   *book,
-				Genres: []models.Genre{},
+		book.ISBN = isbn.String
+		book.Description = description.String
+		book.PublishedYear = int(publishedYear.Int64)
+		book.CoverURL = coverURL.String
+		book.Format = format.String
+		book.Publisher = publisher.String
+		book.PageCount = int(pageCount.Int64)
+		if search != "" {
+			book.Highlights = &models.SearchHighlights{
+				Title:       titleHighlight,
+				Description: descriptionHighlight,
 			}
-			bookOrder = append(bookOrder, bookID)
 		}
+
+		bookMap[book.ID] = book
+		bookOrder = append(bookOrder, book.ID)
 	}
 
-	// Now fetch genres for all books
+	// Now fetch genres and authors for all books
 	bookIDs := make([]int, 0, len(bookMap))
-	for id := range bookMap {
+	plainBooks := make([]*models.Book, 0, len(bookMap))
+	for id, book := range bookMap {
 		bookIDs = append(bookIDs, id)
+		plainBooks = append(plainBooks, &book.Book)
+	}
+	if err := r.attachBookDetails(plainBooks); err != nil {
+		return nil, "", err
 	}
 
 	if len(bookIDs) > 0 {
 		genreQuery := `
-			SELECT bg.book_id, g.id, g.name, g.created_at
+			SELECT bg.book_id, g.id, g.name, g.parent_id, g.created_at
 			FROM book_genres bg
 			JOIN genres g ON bg.genre_id = g.id
 			WHERE bg.book_id = ANY($1)
@@ -312,16 +672,16 @@ FROM books b LEFT JOIN book_genres bg ON b.id = bg.book_id LEFT JOIN genres g ON
 
 		genreRows, err := r.db.Query(genreQuery, pq.Array(bookIDs))
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		defer genreRows.Close()
 
 		for genreRows.Next() {
 			var bookID int
 			var genre models.Genre
-			err := genreRows.Scan(&bookID, &genre.ID, &genre.Name, &genre.CreatedAt)
+			err := genreRows.Scan(&bookID, &genre.ID, &genre.Name, &genre.ParentID, &genre.CreatedAt)
 			if err != nil {
-				return nil, err
+				return nil, "", err
 			}
 			if book, exists := bookMap[bookID]; exists {
 				book.Genres = append(book.Genres, genre)
@@ -329,13 +689,98 @@ FROM books b LEFT JOIN book_genres bg ON b.id = bg.book_id LEFT JOIN genres g ON
 		}
 	}
 
+	workIDs := make([]int, 0, len(bookMap))
+	for _, book := range bookMap {
+		workIDs = append(workIDs, book.WorkID)
+	}
+	topTags, err := topTagsForWorks(r.db, workIDs, 5)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, book := range bookMap {
+		if tags, ok := topTags[book.WorkID]; ok {
+			book.Tags = tags
+		}
+	}
+
 	// Return in original order
 	books := make([]*models.BookWithGenres, 0, len(bookOrder))
 	for _, id := range bookOrder {
 		books = append(books, bookMap[id])
 	}
 
-	return books, nil
+	next := ""
+	if len(books) > 0 {
+		last := books[len(books)-1]
+		next = nextCursor(page, len(books), models.Cursor{Value: last.SearchRank, Time: last.CreatedAt, ID: last.ID})
+	}
+	return books, next, nil
+}
+
+// Facets counts the books matching the filter in total, per genre (including
+// subgenres) and per publication decade.
+func (r *BookRepository) Facets(filter models.BookFilter) (*models.BookFacets, error) {
+	where, args := bookFilterClause(filter)
+	matching := "SELECT b.id, b.published_year FROM books b" + where
+
+	facets := &models.BookFacets{Genres: []models.GenreFacet{}, Decades: []models.DecadeFacet{}}
+
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM ("+matching+") m", args...).Scan(&facets.Total); err != nil {
+		return nil, err
+	}
+
+	genreQuery := `
+		WITH RECURSIVE closure AS (
+			SELECT id AS ancestor_id, id AS genre_id FROM genres
+			UNION
+			SELECT c.ancestor_id, g.id FROM closure c JOIN genres g ON g.parent_id = c.genre_id
+		),
+		matching AS (` + matching + `)
+		SELECT g.id, g.name, COUNT(DISTINCT m.id) AS count
+		FROM genres g
+		JOIN closure c ON c.ancestor_id = g.id
+		JOIN book_genres bg ON bg.genre_id = c.genre_id
+		JOIN matching m ON m.id = bg.book_id
+		GROUP BY g.id, g.name
+		ORDER BY count DESC, g.name ASC`
+
+	rows, err := r.db.Query(genreQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var facet models.GenreFacet
+		if err := rows.Scan(&facet.ID, &facet.Name, &facet.Count); err != nil {
+			return nil, err
+		}
+		facets.Genres = append(facets.Genres, facet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	decadeQuery := `
+		SELECT (m.published_year / 10) * 10 AS decade, COUNT(*)
+		FROM (` + matching + `) m
+		WHERE m.published_year IS NOT NULL
+		GROUP BY decade
+		ORDER BY decade ASC`
+
+	decadeRows, err := r.db.Query(decadeQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer decadeRows.Close()
+	for decadeRows.Next() {
+		var facet models.DecadeFacet
+		if err := decadeRows.Scan(&facet.Decade, &facet.Count); err != nil {
+			return nil, err
+		}
+		facets.Decades = append(facets.Decades, facet)
+	}
+
+	return facets, decadeRows.Err()
 }
 
 func (r *BookRepository) GetByIDWithGenres(id int) (*models.BookWithGenres, error) {
@@ -345,7 +790,7 @@ func (r *BookRepository) GetByIDWithGenres(id int) (*models.BookWithGenres, erro
 	}
 
 	genreQuery := `
-		SELECT g.id, g.name, g.created_at
+		SELECT g.id, g.name, g.parent_id, g.created_at
 		FROM genres g
 		JOIN book_genres bg ON g.id = bg.genre_id
 		WHERE bg.book_id = $1
@@ -360,44 +805,133 @@ func (r *BookRepository) GetByIDWithGenres(id int) (*models.BookWithGenres, erro
 	genres := []models.Genre{}
 	for rows.Next() {
 		var genre models.Genre
-		err := rows.Scan(&genre.ID, &genre.Name, &genre.CreatedAt)
+		err := rows.Scan(&genre.ID, &genre.Name, &genre.ParentID, &genre.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
 		genres = append(genres, genre)
 	}
 
+	editions, err := r.GetEditions(book.WorkID, book.ID)
+	if err != nil {
+		return nil, err
+	}
+	series, err := r.GetSeriesMemberships(book.WorkID)
+	if err != nil {
+		return nil, err
+	}
+	topTags, err := topTagsForWorks(r.db, []int{book.WorkID}, 10)
+	if err != nil {
+		return nil, err
+	}
+	tags := topTags[book.WorkID]
+	if tags == nil {
+		tags = []models.BookTag{}
+	}
+
 	return &models.BookWithGenres{
-		Book:   *book,
-		Genres: genres,
+		Book:     *book,
+		Genres:   genres,
+		Editions: editions,
+		Series:   series,
+		Tags:     tags,
 	}, nil
 }
 
-// GetSimilarBooks - Collaborative filtering
+func (r *BookRepository) GetSeriesMemberships(workID int) ([]models.SeriesMembership, error) {
+	query := `SELECT s.id, s.name, se.position
+FROM series_entries se
+JOIN series s ON se.series_id = s.id
+WHERE se.work_id = $1
+ORDER BY s.name ASC`
+	rows, err := r.db.Query(query, workID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []models.SeriesMembership{}
+	for rows.Next() {
+		var m models.SeriesMembership
+		if err := rows.Scan(&m.SeriesID, &m.Name, &m.Position); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, m)
+	}
+	return memberships, nil
+}
+
+// GetEditions lists the other books that share a work, oldest publication first.
+func (r *BookRepository) GetEditions(workID, excludeBookID int) ([]models.Edition, error) {
+	query := `SELECT id, title, isbn, format, publisher, published_year, cover_url
+FROM books
+WHERE work_id = $1 AND id != $2
+ORDER BY published_year ASC NULLS LAST, id`
+	rows, err := r.db.Query(query, workID, excludeBookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	editions := []models.Edition{}
+	for rows.Next() {
+		var e models.Edition
+		var isbn, format, publisher, coverURL sql.NullString
+		var year sql.NullInt64
+		if err := rows.Scan(&e.BookID, &e.Title, &isbn, &format, &publisher, &year, &coverURL); err != nil {
+			return nil, err
+		}
+		e.ISBN = isbn.String
+		e.Format = format.String
+		e.Publisher = publisher.String
+		e.PublishedYear = int(year.Int64)
+		e.CoverURL = coverURL.String
+		editions = append(editions, e)
+	}
+	return editions, nil
+}
+
+// primaryEditionJoin picks the oldest book row of a work to stand in for it in rolled-up listings.
+const primaryEditionJoin = `JOIN LATERAL (
+			SELECT id, title, author, cover_url FROM books WHERE work_id = %s ORDER BY id LIMIT 1
+		) b ON true`
+
+// GetSimilarBooks - Collaborative filtering, rolled up per work
 func (r *BookRepository) GetSimilarBooks(bookID, limit int) ([]map[string]interface{}, error) {
 	query := `
-		WITH users_who_liked AS (
-			SELECT DISTINCT user_id
-			FROM ratings
-			WHERE book_id = $1 AND rating >= 7
+		WITH target AS (
+			SELECT work_id FROM books WHERE id = $1
+		),
+		users_who_liked AS (
+			SELECT DISTINCT r.user_id
+			FROM ratings r
+			JOIN books b ON r.book_id = b.id
+			WHERE b.work_id = (SELECT work_id FROM target) AND r.rating >= 7
 		),
-		other_books_liked AS (
-			SELECT r.book_id, COUNT(DISTINCT r.user_id) as common_users
+		other_works_liked AS (
+			SELECT b.work_id, COUNT(DISTINCT r.user_id) as common_users
 			FROM ratings r
 			INNER JOIN users_who_liked u ON r.user_id = u.user_id
-			WHERE r.book_id != $1 AND r.rating >= 7
-			GROUP BY r.book_id
+			JOIN books b ON r.book_id = b.id
+			WHERE b.work_id != (SELECT work_id FROM target) AND r.rating >= 7
+			GROUP BY b.work_id
 			HAVING COUNT(DISTINCT r.user_id) >= 2
+		),
+		work_avg AS (
+			SELECT b.work_id, COALESCE(AVG(r.rating), 0) as avg_rating
+			FROM books b
+			LEFT JOIN ratings r ON b.id = r.book_id
+			WHERE b.work_id IN (SELECT work_id FROM other_works_liked)
+			GROUP BY b.work_id
 		)
 		SELECT 
 			b.id, b.title, b.author, b.cover_url,
-			obl.common_users,
-			COALESCE(AVG(r.rating), 0) as avg_rating
-		FROM other_books_liked obl
-		JOIN books b ON obl.book_id = b.id
-		LEFT JOIN ratings r ON b.id = r.book_id
-		GROUP BY b.id, b.title, b.author, b.cover_url, obl.common_users
-		ORDER BY obl.common_users DESC, avg_rating DESC
+			owl.common_users,
+			wa.avg_rating
+		FROM other_works_liked owl
+		JOIN work_avg wa ON owl.work_id = wa.work_id
+		` + fmt.Sprintf(primaryEditionJoin, "owl.work_id") + `
+		ORDER BY owl.common_users DESC, wa.avg_rating DESC
 		LIMIT $2`
 
 	rows, err := r.db.Query(query, bookID, limit)
@@ -430,19 +964,24 @@ func (r *BookRepository) GetSimilarBooks(bookID, limit int) ([]map[string]interf
 	return books, nil
 }
 
-// GetTrendingBooks - Most rated in the last X days
+// GetTrendingBooks - Most rated works in the last X days
 func (r *BookRepository) GetTrendingBooks(days, limit int) ([]map[string]interface{}, error) {
 	query := fmt.Sprintf(`
+		WITH work_stats AS (
+			SELECT b.work_id, COUNT(r.id) as rating_count, COALESCE(AVG(r.rating), 0) as avg_rating
+			FROM ratings r
+			JOIN books b ON r.book_id = b.id
+			WHERE r.created_at >= NOW() - INTERVAL '%d days'
+			GROUP BY b.work_id
+			HAVING COUNT(r.id) >= 1
+		)
 		SELECT 
 			b.id, b.title, b.author, b.cover_url,
-			COUNT(r.id) as rating_count,
-			COALESCE(AVG(r.rating), 0) as avg_rating
-		FROM ratings r
-		JOIN books b ON r.book_id = b.id
-		WHERE r.created_at >= NOW() - INTERVAL '%d days'
-		GROUP BY b.id, b.title, b.author, b.cover_url
-		HAVING COUNT(r.id) >= 1
-		ORDER BY rating_count DESC, avg_rating DESC
+			ws.rating_count,
+			ws.avg_rating
+		FROM work_stats ws
+		` + fmt.Sprintf(primaryEditionJoin, "ws.work_id") + `
+		ORDER BY ws.rating_count DESC, ws.avg_rating DESC
 		LIMIT $1`, days)
 
 	rows, err := r.db.Query(query, limit)
@@ -475,18 +1014,20 @@ func (r *BookRepository) GetTrendingBooks(days, limit int) ([]map[string]interfa
 	return books, nil
 }
 
-// GetPopularBooks - Highest avg rating (min X ratings)
+// GetPopularBooks ranks works by their Bayesian popularity score, so a handful
+// of high ratings can't outrank a slightly lower average over many more.
+// Scores are kept current by triggers on ratings and books.
 func (r *BookRepository) GetPopularBooks(minRatings, limit int) ([]map[string]interface{}, error) {
 	query := `
 		SELECT 
 			b.id, b.title, b.author, b.cover_url,
-			COALESCE(AVG(r.rating), 0) as avg_rating,
-			COUNT(r.id) as rating_count
-		FROM books b
-		JOIN ratings r ON b.id = r.book_id
-		GROUP BY b.id, b.title, b.author, b.cover_url
-		HAVING COUNT(r.id) >= $1
-		ORDER BY avg_rating DESC, rating_count DESC
+			ws.rating_sum::float8 / ws.rating_count AS avg_rating,
+			ws.rating_count,
+			ws.score
+		FROM work_rating_stats ws
+		` + fmt.Sprintf(primaryEditionJoin, "ws.work_id") + `
+		WHERE ws.rating_count >= GREATEST($1, 1)
+		ORDER BY ws.score DESC, ws.rating_count DESC
 		LIMIT $2`
 
 	rows, err := r.db.Query(query, minRatings, limit)
@@ -500,9 +1041,9 @@ func (r *BookRepository) GetPopularBooks(minRatings, limit int) ([]map[string]in
 		var bookID, ratingCount int
 		var title, author string
 		var coverURL sql.NullString
-		var avgRating float64
+		var avgRating, score float64
 
-		err := rows.Scan(&bookID, &title, &author, &coverURL, &avgRating, &ratingCount)
+		err := rows.Scan(&bookID, &title, &author, &coverURL, &avgRating, &ratingCount, &score)
 		if err != nil {
 			return nil, err
 		}
@@ -514,11 +1055,39 @@ func (r *BookRepository) GetPopularBooks(minRatings, limit int) ([]map[string]in
 			"cover_url":    coverURL.String,
 			"avg_rating":   avgRating,
 			"rating_count": ratingCount,
+			"score":        score,
 		})
 	}
 	return books, nil
 }
 
+// SetPopularityPrior stores the prior popularity scores are computed with,
+// rescoring every work if it changed.
+func (r *BookRepository) SetPopularityPrior(prior models.PopularityPrior) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE popularity_prior SET weight = $1, mean = $2 WHERE weight <> $1 OR mean <> $2`, prior.Weight, prior.Mean)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return nil
+	}
+	_, err = tx.Exec(`UPDATE work_rating_stats
+		SET score = COALESCE(($1::float8 * $2::float8 + rating_sum) / NULLIF($1::float8 + rating_count, 0), $2::float8), updated_at = CURRENT_TIMESTAMP`,
+		prior.Weight, prior.Mean)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func nullString(s string) interface{} {
 	if s == "" {
 		return nil
@@ -533,13 +1102,100 @@ func nullInt(i int) interface{} {
 	return i
 }
 
+// coverImportLockKey is the advisory lock held by whichever API instance is
+// running the remote cover importer.
+const coverImportLockKey = 7244101
+
+// TryCoverImportLock takes the cover importer's advisory lock if no other
+// instance holds it. The lock lives on its own connection until release is
+// called.
+func (r *BookRepository) TryCoverImportLock() (release func(), ok bool, err error) {
+	ctx := context.Background()
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, coverImportLockKey).Scan(&ok); err != nil || !ok {
+		conn.Close()
+		return nil, false, err
+	}
+	release = func() {
+		conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, coverImportLockKey)
+		conn.Close()
+	}
+	return release, true, nil
+}
+
+// ListRemoteCovers returns books whose cover_url still points at another site,
+// skipping ones that failed to download in the last week.
+func (r *BookRepository) ListRemoteCovers(limit int) ([]models.RemoteCover, error) {
+	query := `SELECT id, cover_url FROM books
+WHERE cover_url LIKE 'http%'
+	AND (cover_fetch_failed_at IS NULL OR cover_fetch_failed_at < NOW() - INTERVAL '7 days')
+ORDER BY id
+LIMIT $1`
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	covers := []models.RemoteCover{}
+	for rows.Next() {
+		var c models.RemoteCover
+		if err := rows.Scan(&c.BookID, &c.URL); err != nil {
+			return nil, err
+		}
+		covers = append(covers, c)
+	}
+	return covers, rows.Err()
+}
+
+// ReplaceRemoteCover swaps a hotlinked cover for a stored copy, keeping the
+// original URL. It does nothing if the cover was edited in the meantime.
+func (r *BookRepository) ReplaceRemoteCover(bookID int, remoteURL, localURL string) (bool, error) {
+	query := `UPDATE books SET cover_url = $3, cover_source_url = $2, cover_fetch_failed_at = NULL
+WHERE id = $1 AND cover_url = $2`
+	result, err := r.db.Exec(query, bookID, remoteURL, localURL)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+func (r *BookRepository) MarkCoverFetchFailed(bookID int) error {
+	_, err := r.db.Exec(`UPDATE books SET cover_fetch_failed_at = CURRENT_TIMESTAMP WHERE id = $1`, bookID)
+	return err
+}
+
+// GetByISBN looks a book up by ISBN-13, also matching rows still stored in ISBN-10 form.
+func (r *BookRepository) GetByISBN(isbn string) (*models.Book, error) {
+	var id int
+	err := r.db.QueryRow(`SELECT id FROM books WHERE isbn = ANY($1) ORDER BY id LIMIT 1`, pq.Array(models.ISBNForms(isbn))).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("book not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(id)
+}
+
+// FindByTitleAuthor matches on title plus either the display string or any single credited author.
 func (r *BookRepository) FindByTitleAuthor(title, author string) (*models.Book, error) {
-	query := `SELECT id, title, author, isbn, description, published_year, cover_url, created_at, updated_at FROM books WHERE LOWER(title) = LOWER($1) AND LOWER(author) = LOWER($2) LIMIT 1`
+	query := `SELECT b.id, b.title, b.author, b.isbn, b.description, b.published_year, b.cover_url, b.work_id, b.format, b.publisher, b.page_count, b.created_at, b.updated_at
+FROM books b
+WHERE LOWER(b.title) = LOWER($1)
+	AND (LOWER(b.author) = LOWER($2) OR EXISTS (
+		SELECT 1 FROM book_authors ba JOIN authors a ON ba.author_id = a.id
+		WHERE ba.book_id = b.id AND LOWER(a.name) = LOWER($2)))
+LIMIT 1`
 	book := &models.Book{}
-	var isbnNull, descNull, coverNull sql.NullString
-	var yearNull sql.NullInt64
+	var isbnNull, descNull, coverNull, formatNull, publisherNull sql.NullString
+	var yearNull, pageCountNull sql.NullInt64
 	
-	err := r.db.QueryRow(query, title, author).Scan(&book.ID, &book.Title, &book.Author, &isbnNull, &descNull, &yearNull, &coverNull, &book.CreatedAt, &book.UpdatedAt)
+	err := r.db.QueryRow(query, title, author).Scan(&book.ID, &book.Title, &book.Author, &isbnNull, &descNull, &yearNull, &coverNull, &book.WorkID, &formatNull, &publisherNull, &pageCountNull, &book.CreatedAt, &book.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, err
 	}
@@ -549,8 +1205,136 @@ func (r *BookRepository) FindByTitleAuthor(title, author string) (*models.Book,
 	book.CoverURL = coverNull.String
 	book.Description = descNull.String
 	book.ISBN = isbnNull.String
+	book.Format = formatNull.String
+	book.Publisher = publisherNull.String
+	book.PageCount = int(pageCountNull.Int64)
 	if yearNull.Valid {
 		book.PublishedYear = int(yearNull.Int64)
 	}
+	if err := r.attachBookDetails([]*models.Book{book}); err != nil {
+		return nil, err
+	}
 	return book, nil
-}
\ No newline at end of file
+}
+
+// lockBookForUpdate loads a book and its credits inside tx, locking the row so
+// the revision diff can't race with a concurrent edit.
+func lockBookForUpdate(tx *sql.Tx, id int) (*models.Book, error) {
+	book := &models.Book{Authors: []models.BookAuthor{}}
+	var isbn, description, coverURL, format, publisher sql.NullString
+	var publishedYear, pageCount sql.NullInt64
+	err := tx.QueryRow(`SELECT id, title, author, isbn, description, published_year, cover_url, work_id, format, publisher, page_count
+FROM books WHERE id = $1 FOR UPDATE`, id).Scan(
+		&book.ID, &book.Title, &book.Author, &isbn, &description, &publishedYear, &coverURL, &book.WorkID, &format, &publisher, &pageCount)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("book not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	book.ISBN = isbn.String
+	book.Description = description.String
+	book.PublishedYear = int(publishedYear.Int64)
+	book.CoverURL = coverURL.String
+	book.Format = format.String
+	book.Publisher = publisher.String
+	book.PageCount = int(pageCount.Int64)
+
+	rows, err := tx.Query(`SELECT a.id, a.name, ba.role
+FROM book_authors ba
+JOIN authors a ON ba.author_id = a.id
+WHERE ba.book_id = $1
+ORDER BY ba.position`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var author models.BookAuthor
+		if err := rows.Scan(&author.ID, &author.Name, &author.Role); err != nil {
+			return nil, err
+		}
+		book.Authors = append(book.Authors, author)
+	}
+	return book, rows.Err()
+}
+
+func isISBNConflict(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505" && pqErr.Constraint == "books_isbn_key"
+}
+
+func workExists(tx *sql.Tx, workID int) error {
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM works WHERE id = $1)`, workID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("work not found")
+	}
+	return nil
+}
+
+// setBookAuthors replaces a book's author credits, creating authors that don't exist yet.
+// Callers are responsible for keeping books.author in sync via models.AuthorDisplayName.
+func setBookAuthors(tx *sql.Tx, bookID int, credits []models.AuthorCredit) ([]models.BookAuthor, error) {
+	if _, err := tx.Exec(`DELETE FROM book_authors WHERE book_id = $1`, bookID); err != nil {
+		return nil, err
+	}
+	authors := []models.BookAuthor{}
+	for i, credit := range credits {
+		author := models.BookAuthor{Name: credit.Name, Role: credit.Role}
+		err := tx.QueryRow(`INSERT INTO authors (name) VALUES ($1)
+ON CONFLICT ((LOWER(name))) DO UPDATE SET name = authors.name
+RETURNING id, name`, credit.Name).Scan(&author.ID, &author.Name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(`INSERT INTO book_authors (book_id, author_id, role, position) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+			bookID, author.ID, credit.Role, i)
+		if err != nil {
+			return nil, err
+		}
+		authors = append(authors, author)
+	}
+	return authors, nil
+}
+
+// attachBookDetails loads author credits for a batch of books in one query
+// and fills in thumbnail URLs for locally stored covers.
+func (r *BookRepository) attachBookDetails(books []*models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	bookIDs := make([]int, 0, len(books))
+	byID := make(map[int][]*models.Book, len(books))
+	for _, book := range books {
+		book.Authors = []models.BookAuthor{}
+		book.Thumbnails = models.CoverThumbnails(book.CoverURL)
+		bookIDs = append(bookIDs, book.ID)
+		byID[book.ID] = append(byID[book.ID], book)
+	}
+
+	query := `SELECT ba.book_id, a.id, a.name, ba.role
+FROM book_authors ba
+JOIN authors a ON ba.author_id = a.id
+WHERE ba.book_id = ANY($1)
+ORDER BY ba.book_id, ba.position`
+	rows, err := r.db.Query(query, pq.Array(bookIDs))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID int
+		var author models.BookAuthor
+		if err := rows.Scan(&bookID, &author.ID, &author.Name, &author.Role); err != nil {
+			return err
+		}
+		for _, book := range byID[bookID] {
+			book.Authors = append(book.Authors, author)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+// testDB connects to the database named by TEST_DATABASE_URL, which must
+// already be migrated. Tests that need it are skipped when it isn't set.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestListWithGenresSearchTiesAcrossPages(t *testing.T) {
+	db := testDB(t)
+	repo := NewBookRepository(db)
+
+	var workID int
+	if err := db.QueryRow(`INSERT INTO works (title) VALUES ('Zyzzogeton Tie') RETURNING id`).Scan(&workID); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM books WHERE work_id = $1`, workID)
+		db.Exec(`DELETE FROM works WHERE id = $1`, workID)
+	})
+
+	// Identical text and created_at, so every row has the same rank and
+	// only the id separates them
+	want := map[int]bool{}
+	for i := 0; i < 5; i++ {
+		var id int
+		err := db.QueryRow(`INSERT INTO books (title, author, work_id, created_at)
+			VALUES ('Zyzzogeton Tie', 'Tie Author', $1, '2024-01-01 00:00:00.123456')
+			RETURNING id`, workID).Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[id] = true
+	}
+
+	filter := models.BookFilter{Search: "zyzzogeton"}
+	page := models.PageParams{Limit: 2}
+	seen := map[int]int{}
+	for pages := 0; pages < 10; pages++ {
+		books, next, err := repo.ListWithGenres(page, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, b := range books {
+			seen[b.ID]++
+		}
+		if next == "" {
+			break
+		}
+		after, err := models.DecodeCursor(next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page.After = after
+	}
+
+	for id := range want {
+		if seen[id] != 1 {
+			t.Errorf("book %d returned %d times, want once", id, seen[id])
+		}
+	}
+	for id := range seen {
+		if !want[id] {
+			t.Errorf("unexpected book %d in results", id)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package database
 
 import (
 	"database/sql"
+
+	"github.com/pulkyeet/BookmarkD/internal/markdown"
 	"github.com/pulkyeet/BookmarkD/internal/models"
 )
 
@@ -14,43 +16,64 @@ func NewCommentRepository(db *sql.DB) *CommentRepository {
 }
 
 func (r *CommentRepository) Create(userID, ratingID int, text string) (*models.Comment, error) {
-	query := `INSERT INTO comments (user_id, rating_id, text) VALUES ($1, $2, $3) RETURNING id, user_id, rating_id, text, created_at`
+	query := `INSERT INTO comments (user_id, rating_id, text, text_html) VALUES ($1, $2, $3, $4) RETURNING id, user_id, rating_id, text, text_html, created_at`
 
 	comment := &models.Comment{}
-	err := r.db.QueryRow(query, userID, ratingID, text).Scan(&comment.ID, &comment.UserID, &comment.RatingID, &comment.Text, &comment.CreatedAt)
+	err := r.db.QueryRow(query, userID, ratingID, text, markdown.Render(text)).Scan(&comment.ID, &comment.UserID, &comment.RatingID, &comment.Text, &comment.TextHTML, &comment.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return comment, nil
 }
 
-func (r *CommentRepository) GetByRatingID(ratingID int) ([]models.CommentWithUser, error) {
-	query := `SELECT c.id, c.user_id, c.rating_id, c.text, c.created_at, u.username
+// GetByRatingID returns a review's comments oldest first along with the cursor for the next page.
+func (r *CommentRepository) GetByRatingID(ratingID int, page models.PageParams) ([]models.CommentWithUser, string, error) {
+	query := `SELECT c.id, c.user_id, c.rating_id, c.text, c.text_html, c.created_at, u.username
 FROM comments c
 JOIN users u ON c.user_id = u.id
 WHERE c.rating_id = $1`
+	args := []interface{}{ratingID}
+	argCount := 2
+	if page.After != nil {
+		cond, keysetArgs := keysetCondition(page.After, []string{"c.created_at", "c.id"}, false, argCount)
+		query += ` AND ` + cond
+		args = append(args, keysetArgs...)
+		argCount += len(keysetArgs)
+	}
+	query += ` ORDER BY c.created_at ASC, c.id ASC`
+	limitClause, limitArgs := pageLimit(page, argCount)
+	query += limitClause
+	args = append(args, limitArgs...)
 
-	rows, err := r.db.Query(query, ratingID)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 	comments := []models.CommentWithUser{}
 	for rows.Next() {
 		var comment models.CommentWithUser
+		var textHTMLNull sql.NullString
 		err := rows.Scan(
 			&comment.ID,
 			&comment.UserID,
 			&comment.RatingID,
 			&comment.Text,
+			&textHTMLNull,
 			&comment.CreatedAt,
 			&comment.Username)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
+		comment.TextHTML = renderedHTML(textHTMLNull, comment.Text)
 		comments = append(comments, comment)
 	}
-	return comments, nil
+	next := ""
+	if len(comments) > 0 {
+		last := comments[len(comments)-1]
+		next = nextCursor(page, len(comments), models.Cursor{Time: last.CreatedAt, ID: last.ID})
+	}
+	return comments, next, nil
 }
 
 func (r *CommentRepository) Delete(commentID, userID int) error {
@@ -0,0 +1,390 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+// Thresholds for treating a trigram match as a duplicate on its own,
+// without an ISBN or normalized key match to back it up.
+const (
+	duplicateTitleSimilarity  = 0.6
+	duplicateAuthorSimilarity = 0.5
+)
+
+type DuplicateRepository struct {
+	db *sql.DB
+}
+
+func NewDuplicateRepository(db *sql.DB) *DuplicateRepository {
+	return &DuplicateRepository{db: db}
+}
+
+// FindCandidates returns books that look like duplicates of bookID, combining
+// ISBN-10/13 equivalence, the normalized title/author key and trigram similarity.
+func (r *DuplicateRepository) FindCandidates(bookID, limit int) ([]models.DuplicateCandidate, error) {
+	var title, author string
+	var isbn sql.NullString
+	err := r.db.QueryRow(`SELECT title, author, isbn FROM books WHERE id = $1`, bookID).Scan(&title, &author, &isbn)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("book not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	isbnForms := []string{}
+	if isbn.String != "" {
+		isbnForms = models.ISBNForms(isbn.String)
+	}
+
+	// The trigram operators only narrow the search; the final decision is made below
+	query := `
+		SELECT b.id, b.title, b.author, b.isbn, b.published_year, b.cover_url,
+		       (SELECT COUNT(*) FROM ratings r WHERE r.book_id = b.id) AS rating_count,
+		       similarity(b.title, $2) AS title_similarity,
+		       similarity(b.author, $3) AS author_similarity
+		FROM books b
+		WHERE b.id <> $1
+		  AND (b.title % $2 OR $2 <% b.title OR b.title <% $2 OR b.isbn = ANY($4))
+		ORDER BY title_similarity DESC
+		LIMIT 200`
+
+	rows, err := r.db.Query(query, bookID, title, author, pq.Array(isbnForms))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	key := models.NormalizeBookKey(title, author)
+	isbnKey := ""
+	if isbn.String != "" {
+		isbnKey = models.ISBNMatchKey(isbn.String)
+	}
+
+	candidates := []models.DuplicateCandidate{}
+	for rows.Next() {
+		var c models.DuplicateCandidate
+		var cIsbn, coverURL sql.NullString
+		var year sql.NullInt64
+		var authorSimilarity float64
+		err := rows.Scan(&c.BookID, &c.Title, &c.Author, &cIsbn, &year, &coverURL, &c.RatingCount, &c.TitleSimilarity, &authorSimilarity)
+		if err != nil {
+			return nil, err
+		}
+		c.ISBN = cIsbn.String
+		c.PublishedYear = int(year.Int64)
+		c.CoverURL = coverURL.String
+
+		c.Reasons = []string{}
+		if isbnKey != "" && c.ISBN != "" && models.ISBNMatchKey(c.ISBN) == isbnKey {
+			c.Reasons = append(c.Reasons, "isbn")
+		}
+		if !strings.HasPrefix(key, "|") && models.NormalizeBookKey(c.Title, c.Author) == key {
+			c.Reasons = append(c.Reasons, "normalized_key")
+		}
+		if c.TitleSimilarity >= duplicateTitleSimilarity && authorSimilarity >= duplicateAuthorSimilarity {
+			c.Reasons = append(c.Reasons, "title_similarity")
+		}
+		if len(c.Reasons) > 0 {
+			candidates = append(candidates, c)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if len(candidates[i].Reasons) != len(candidates[j].Reasons) {
+			return len(candidates[i].Reasons) > len(candidates[j].Reasons)
+		}
+		return candidates[i].TitleSimilarity > candidates[j].TitleSimilarity
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// FindGroups scans the catalog for books sharing a normalized key or an ISBN.
+// Both keys are computed in Go, so this reads every book once.
+func (r *DuplicateRepository) FindGroups(limit int) ([]models.DuplicateGroup, error) {
+	query := `
+		SELECT b.id, b.title, b.author, b.isbn, b.published_year, b.cover_url, COALESCE(rc.count, 0)
+		FROM books b
+		LEFT JOIN (SELECT book_id, COUNT(*) AS count FROM ratings GROUP BY book_id) rc ON rc.book_id = b.id
+		ORDER BY b.id`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byKey := map[string][]models.DuplicateCandidate{}
+	byISBN := map[string][]models.DuplicateCandidate{}
+	for rows.Next() {
+		var c models.DuplicateCandidate
+		var isbn, coverURL sql.NullString
+		var year sql.NullInt64
+		if err := rows.Scan(&c.BookID, &c.Title, &c.Author, &isbn, &year, &coverURL, &c.RatingCount); err != nil {
+			return nil, err
+		}
+		c.ISBN = isbn.String
+		c.PublishedYear = int(year.Int64)
+		c.CoverURL = coverURL.String
+
+		// A title with nothing left after normalizing says nothing about the book
+		if key := models.NormalizeBookKey(c.Title, c.Author); !strings.HasPrefix(key, "|") {
+			byKey[key] = append(byKey[key], c)
+		}
+		if c.ISBN != "" {
+			isbnKey := models.ISBNMatchKey(c.ISBN)
+			byISBN[isbnKey] = append(byISBN[isbnKey], c)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	groups := []models.DuplicateGroup{}
+	for key, books := range byISBN {
+		if len(books) > 1 {
+			groups = append(groups, models.DuplicateGroup{Reason: "isbn", Key: key, Books: withReason(books, "isbn")})
+		}
+	}
+	for key, books := range byKey {
+		if len(books) > 1 {
+			groups = append(groups, models.DuplicateGroup{Reason: "normalized_key", Key: key, Books: withReason(books, "normalized_key")})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].Books) != len(groups[j].Books) {
+			return len(groups[i].Books) > len(groups[j].Books)
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	if len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups, nil
+}
+
+func withReason(books []models.DuplicateCandidate, reason string) []models.DuplicateCandidate {
+	for i := range books {
+		books[i].Reasons = []string{reason}
+	}
+	return books
+}
+
+// Merge folds loserID into winnerID and deletes the loser. Ratings, likes,
+// comments, list entries, genres, series membership and tag votes all move
+// to the winner in one transaction. When a user rated both books, the more
+// recently updated rating is kept and the other one's likes, comments and reads move onto it.
+// It returns the IDs of users whose ratings were touched so callers can
+// invalidate their caches.
+func (r *DuplicateRepository) Merge(winnerID, loserID int) ([]int, error) {
+	if winnerID == loserID {
+		return nil, fmt.Errorf("cannot merge a book into itself")
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var winnerWorkID, loserWorkID int
+	rows, err := tx.Query(`SELECT id, work_id FROM books WHERE id = ANY($1) ORDER BY id FOR UPDATE`, pq.Array([]int{winnerID, loserID}))
+	if err != nil {
+		return nil, err
+	}
+	found := 0
+	for rows.Next() {
+		var id, workID int
+		if err := rows.Scan(&id, &workID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if id == winnerID {
+			winnerWorkID = workID
+		} else {
+			loserWorkID = workID
+		}
+		found++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if found != 2 {
+		return nil, fmt.Errorf("book not found")
+	}
+
+	// Users who rated both books: keep one rating and fold the other into it
+	type ratingPair struct {
+		keep, drop int
+	}
+	pairs := []ratingPair{}
+	userIDs := []int{}
+	rows, err = tx.Query(`
+		SELECT l.user_id, w.id, l.id, l.updated_at > w.updated_at
+		FROM ratings l
+		JOIN ratings w ON w.user_id = l.user_id AND w.book_id = $1
+		WHERE l.book_id = $2`, winnerID, loserID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var userID, winnerRatingID, loserRatingID int
+		var loserNewer bool
+		if err := rows.Scan(&userID, &winnerRatingID, &loserRatingID, &loserNewer); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+		if loserNewer {
+			pairs = append(pairs, ratingPair{keep: loserRatingID, drop: winnerRatingID})
+		} else {
+			pairs = append(pairs, ratingPair{keep: winnerRatingID, drop: loserRatingID})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, p := range pairs {
+		_, err := tx.Exec(`
+			INSERT INTO review_likes (user_id, rating_id, created_at)
+			SELECT user_id, $1, created_at FROM review_likes WHERE rating_id = $2
+			ON CONFLICT DO NOTHING`, p.keep, p.drop)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`UPDATE comments SET rating_id = $1 WHERE rating_id = $2`, p.keep, p.drop); err != nil {
+			return nil, err
+		}
+		// Ended reads from both entries are kept; only one read can stay in progress
+		_, err = tx.Exec(`
+			UPDATE reading_sessions SET rating_id = $1
+			WHERE rating_id = $2
+			  AND (finished OR stopped_at IS NOT NULL
+			       OR NOT EXISTS (SELECT 1 FROM reading_sessions WHERE rating_id = $1 AND NOT finished AND stopped_at IS NULL))`, p.keep, p.drop)
+		if err != nil {
+			return nil, err
+		}
+		// Revisions would cascade away with the dropped entry. Its current
+		// review is kept too: as the review if the kept entry has none,
+		// otherwise as an earlier revision.
+		if _, err := tx.Exec(`UPDATE review_revisions SET rating_id = $1 WHERE rating_id = $2`, p.keep, p.drop); err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(`
+			INSERT INTO review_revisions (rating_id, review, review_html, review_is_spoiler, written_at)
+			SELECT k.id, d.review, d.review_html, d.review_is_spoiler, COALESCE(d.edited_at, d.created_at)
+			FROM ratings d, ratings k
+			WHERE d.id = $2 AND k.id = $1
+			  AND COALESCE(d.review, '') <> '' AND COALESCE(k.review, '') <> ''
+			  AND d.review <> k.review`, p.keep, p.drop)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(`
+			UPDATE ratings k SET review = d.review, review_html = d.review_html,
+				review_is_spoiler = d.review_is_spoiler, review_language = d.review_language, edited_at = d.edited_at
+			FROM ratings d
+			WHERE k.id = $1 AND d.id = $2
+			  AND COALESCE(k.review, '') = '' AND COALESCE(d.review, '') <> ''`, p.keep, p.drop)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`DELETE FROM ratings WHERE id = $1`, p.drop); err != nil {
+			return nil, err
+		}
+		if err := refreshEntry(tx, p.keep, false); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err = tx.Query(`UPDATE ratings SET book_id = $1 WHERE book_id = $2 RETURNING user_id`, winnerID, loserID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A list can only hold a book once, so drop loser entries already covered by the winner
+	_, err = tx.Exec(`
+		DELETE FROM list_books l
+		WHERE l.book_id = $2
+		  AND EXISTS (SELECT 1 FROM list_books w WHERE w.list_id = l.list_id AND w.book_id = $1)`, winnerID, loserID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE list_books SET book_id = $1 WHERE book_id = $2`, winnerID, loserID); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO shelf_books (shelf_id, book_id, added_at)
+		SELECT shelf_id, $1, added_at FROM shelf_books WHERE book_id = $2
+		ON CONFLICT DO NOTHING`, winnerID, loserID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO book_genres (book_id, genre_id)
+		SELECT $1, genre_id FROM book_genres WHERE book_id = $2
+		ON CONFLICT DO NOTHING`, winnerID, loserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if loserWorkID != winnerWorkID {
+		_, err = tx.Exec(`
+			INSERT INTO series_entries (series_id, work_id, position, added_at)
+			SELECT series_id, $1, position, added_at FROM series_entries WHERE work_id = $2
+			ON CONFLICT DO NOTHING`, winnerWorkID, loserWorkID)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(`
+			INSERT INTO tag_votes (work_id, tag_id, user_id, created_at)
+			SELECT $1, tag_id, user_id, created_at FROM tag_votes WHERE work_id = $2
+			ON CONFLICT DO NOTHING`, winnerWorkID, loserWorkID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM books WHERE id = $1`, loserID); err != nil {
+		return nil, err
+	}
+	if loserWorkID != winnerWorkID {
+		_, err := tx.Exec(`DELETE FROM works w WHERE w.id = $1 AND NOT EXISTS (SELECT 1 FROM books b WHERE b.work_id = w.id)`, loserWorkID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
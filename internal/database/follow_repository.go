@@ -40,54 +40,82 @@ func (r *FollowRepository) IsFollowing(followerID, followingID int) (bool, error
 	return exists, err
 }
 
-func (r *FollowRepository) GetFollowers(userID int) ([]models.User, error) {
-	query := `SELECT u.id, u.email, u.username, u.created_at, u.updated_at
+// GetFollowers returns the users following userID, newest follow first, and the next page cursor.
+func (r *FollowRepository) GetFollowers(userID int, page models.PageParams) ([]models.User, string, error) {
+	query := `SELECT u.id, u.email, u.username, u.created_at, u.updated_at, f.created_at
 	FROM users u
 	JOIN follows f ON u.id = f.follower_id
-	WHERE f.following_id = $1
-	ORDER BY f.created_at DESC`
+	WHERE f.following_id = $1`
+	args := []interface{}{userID}
+	argCount := 2
+	if page.After != nil {
+		cond, keysetArgs := keysetCondition(page.After, []string{"f.created_at", "u.id"}, true, argCount)
+		query += ` AND ` + cond
+		args = append(args, keysetArgs...)
+		argCount += len(keysetArgs)
+	}
+	query += ` ORDER BY f.created_at DESC, u.id DESC`
+	limitClause, limitArgs := pageLimit(page, argCount)
+	query += limitClause
+	args = append(args, limitArgs...)
 
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
 	users := []models.User{}
+	var last models.Cursor
 	for rows.Next() {
 		var u models.User
-		err := rows.Scan(&u.ID, &u.Email, &u.Username, &u.CreatedAt, &u.UpdatedAt)
+		err := rows.Scan(&u.ID, &u.Email, &u.Username, &u.CreatedAt, &u.UpdatedAt, &last.Time)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
+		last.ID = u.ID
 		users = append(users, u)
 	}
-	return users, nil
+	return users, nextCursor(page, len(users), last), nil
 }
 
-func (r *FollowRepository) GetFollowing(userID int) ([]models.User, error) {
-	query := `SELECT u.id, u.email, u.username, u.created_at, u.updated_at
+// GetFollowing returns the users userID follows, newest follow first, and the next page cursor.
+func (r *FollowRepository) GetFollowing(userID int, page models.PageParams) ([]models.User, string, error) {
+	query := `SELECT u.id, u.email, u.username, u.created_at, u.updated_at, f.created_at
 	FROM users u
 	JOIN follows f ON u.id = f.following_id
-	WHERE f.follower_id = $1
-	ORDER BY f.created_at DESC`
+	WHERE f.follower_id = $1`
+	args := []interface{}{userID}
+	argCount := 2
+	if page.After != nil {
+		cond, keysetArgs := keysetCondition(page.After, []string{"f.created_at", "u.id"}, true, argCount)
+		query += ` AND ` + cond
+		args = append(args, keysetArgs...)
+		argCount += len(keysetArgs)
+	}
+	query += ` ORDER BY f.created_at DESC, u.id DESC`
+	limitClause, limitArgs := pageLimit(page, argCount)
+	query += limitClause
+	args = append(args, limitArgs...)
 
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
 	users := []models.User{}
+	var last models.Cursor
 	for rows.Next() {
 		var u models.User
-		err := rows.Scan(&u.ID, &u.Email, &u.Username, &u.CreatedAt, &u.UpdatedAt)
+		err := rows.Scan(&u.ID, &u.Email, &u.Username, &u.CreatedAt, &u.UpdatedAt, &last.Time)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
+		last.ID = u.ID
 		users = append(users, u)
 	}
-	return users, nil
+	return users, nextCursor(page, len(users), last), nil
 }
 
 func (r *FollowRepository) GetFollowCounts(userID int) (int, int, error) {
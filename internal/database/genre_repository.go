@@ -2,6 +2,10 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
 	"github.com/pulkyeet/BookmarkD/internal/models"
 )
 
@@ -14,7 +18,7 @@ func NewGenreRepository(db *sql.DB) *GenreRepository {
 }
 
 func (r *GenreRepository) GetAll() ([]models.Genre, error) {
-	query := `SELECT id, name, created_at FROM genres ORDER BY name ASC`
+	query := `SELECT id, name, parent_id, created_at FROM genres ORDER BY name ASC`
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -23,7 +27,7 @@ func (r *GenreRepository) GetAll() ([]models.Genre, error) {
 	genres := []models.Genre{}
 	for rows.Next() {
 		var genre models.Genre
-		err := rows.Scan(&genre.ID, &genre.Name, &genre.CreatedAt)
+		err := rows.Scan(&genre.ID, &genre.Name, &genre.ParentID, &genre.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -33,7 +37,7 @@ func (r *GenreRepository) GetAll() ([]models.Genre, error) {
 }
 
 func (r *GenreRepository) GetByBookID(bookID int) ([]models.Genre, error) {
-	query := `SELECT g.id, g.name, g.created_at FROM genres g JOIN book_genres b ON g.id = b.genre_id WHERE b.book_id = $1 ORDER BY g.name ASC`
+	query := `SELECT g.id, g.name, g.parent_id, g.created_at FROM genres g JOIN book_genres b ON g.id = b.genre_id WHERE b.book_id = $1 ORDER BY g.name ASC`
 	rows, err := r.db.Query(query, bookID)
 	if err != nil {
 		return nil, err
@@ -42,7 +46,7 @@ func (r *GenreRepository) GetByBookID(bookID int) ([]models.Genre, error) {
 	genres := []models.Genre{}
 	for rows.Next() {
 		var genre models.Genre
-		err := rows.Scan(&genre.ID, &genre.Name, &genre.CreatedAt)
+		err := rows.Scan(&genre.ID, &genre.Name, &genre.ParentID, &genre.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -54,13 +58,32 @@ func (r *GenreRepository) GetByBookID(bookID int) ([]models.Genre, error) {
 func (r *GenreRepository) AddGenreToBook(bookID, genreID int) error {
 	query := `INSERT INTO book_genres (book_id, genre_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
 	_, err := r.db.Exec(query, bookID, genreID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		return sql.ErrNoRows
+	}
 	return err
 }
 
+func (r *GenreRepository) RemoveGenreFromBook(bookID, genreID int) error {
+	query := `DELETE FROM book_genres WHERE book_id = $1 AND genre_id = $2`
+	result, err := r.db.Exec(query, bookID, genreID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 func (r *GenreRepository) GetByName(name string) (*models.Genre, error) {
-	query := `SELECT id, name, created_at FROM genres WHERE LOWER(name) = LOWER($1)`
+	query := `SELECT id, name, parent_id, created_at FROM genres WHERE LOWER(name) = LOWER($1)`
 	genre := &models.Genre{}
-	err := r.db.QueryRow(query, name).Scan(&genre.ID, &genre.Name, &genre.CreatedAt)
+	err := r.db.QueryRow(query, name).Scan(&genre.ID, &genre.Name, &genre.ParentID, &genre.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -69,3 +92,173 @@ func (r *GenreRepository) GetByName(name string) (*models.Genre, error) {
 	}
 	return genre, nil
 }
+
+func (r *GenreRepository) GetByID(id int) (*models.Genre, error) {
+	query := `SELECT id, name, parent_id, created_at FROM genres WHERE id = $1`
+	genre := &models.Genre{}
+	err := r.db.QueryRow(query, id).Scan(&genre.ID, &genre.Name, &genre.ParentID, &genre.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return genre, nil
+}
+
+func (r *GenreRepository) Create(req models.CreateGenreRequest) (*models.Genre, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if req.ParentID != nil {
+		if err := genreExists(tx, *req.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	genre := &models.Genre{}
+	err = tx.QueryRow(`INSERT INTO genres (name, parent_id) VALUES ($1, $2) RETURNING id, name, parent_id, created_at`,
+		strings.TrimSpace(req.Name), req.ParentID).Scan(&genre.ID, &genre.Name, &genre.ParentID, &genre.CreatedAt)
+	if isUniqueViolation(err) {
+		return nil, models.ErrGenreExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return genre, nil
+}
+
+// Update renames and/or reparents a genre, refusing moves that would create a cycle.
+func (r *GenreRepository) Update(id int, req models.UpdateGenreRequest) (*models.Genre, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := genreExists(tx, id); err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		_, err := tx.Exec(`UPDATE genres SET name = $1 WHERE id = $2`, strings.TrimSpace(*req.Name), id)
+		if isUniqueViolation(err) {
+			return nil, models.ErrGenreExists
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if req.ParentID != nil {
+		var parentID interface{}
+		if *req.ParentID != 0 {
+			if err := genreExists(tx, *req.ParentID); err != nil {
+				return nil, err
+			}
+			var cycle bool
+			err := tx.QueryRow(`WITH RECURSIVE tree AS (
+	SELECT id FROM genres WHERE id = $1
+	UNION
+	SELECT g.id FROM genres g JOIN tree t ON g.parent_id = t.id
+)
+SELECT EXISTS(SELECT 1 FROM tree WHERE id = $2)`, id, *req.ParentID).Scan(&cycle)
+			if err != nil {
+				return nil, err
+			}
+			if cycle {
+				return nil, models.ErrGenreCycle
+			}
+			parentID = *req.ParentID
+		}
+		if _, err := tx.Exec(`UPDATE genres SET parent_id = $1 WHERE id = $2`, parentID, id); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(id)
+}
+
+// Delete removes a genre and its book assignments. Subgenres move up to top level.
+func (r *GenreRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM genres WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Merge folds sourceID into targetID: books and subgenres of the source move
+// to the target and the source is deleted.
+func (r *GenreRepository) Merge(sourceID, targetID int) (*models.Genre, error) {
+	if sourceID == targetID {
+		return nil, fmt.Errorf("cannot merge a genre into itself")
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var sourceParent sql.NullInt64
+	err = tx.QueryRow(`SELECT parent_id FROM genres WHERE id = $1 FOR UPDATE`, sourceID).Scan(&sourceParent)
+	if err != nil {
+		return nil, err
+	}
+	if err := genreExists(tx, targetID); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO book_genres (book_id, genre_id)
+SELECT book_id, $2 FROM book_genres WHERE genre_id = $1
+ON CONFLICT DO NOTHING`, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	// If the target sits anywhere below the source it takes the source's place,
+	// otherwise reparenting the source's children onto it would form a cycle
+	_, err = tx.Exec(`WITH RECURSIVE tree AS (
+	SELECT id FROM genres WHERE parent_id = $3
+	UNION
+	SELECT g.id FROM genres g JOIN tree t ON g.parent_id = t.id
+)
+UPDATE genres SET parent_id = $1 WHERE id = $2 AND id IN (SELECT id FROM tree)`, sourceParent, targetID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE genres SET parent_id = $1 WHERE parent_id = $2`, targetID, sourceID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM genres WHERE id = $1`, sourceID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(targetID)
+}
+
+func genreExists(tx *sql.Tx, id int) error {
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM genres WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
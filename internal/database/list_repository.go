@@ -66,13 +66,26 @@ ORDER BY lb.position ASC`
 	return list, nil
 }
 
-func (r *ListRepository) GetByUserID(userID int) ([]models.List, error) {
+// GetByUserID returns a user's lists newest first along with the cursor for the next page.
+func (r *ListRepository) GetByUserID(userID int, page models.PageParams) ([]models.List, string, error) {
 	query := `SELECT id, user_id, name, description, public, created_at, updated_at 
-			  FROM lists WHERE user_id = $1 ORDER BY created_at DESC`
+			  FROM lists WHERE user_id = $1`
+	args := []interface{}{userID}
+	argCount := 2
+	if page.After != nil {
+		cond, keysetArgs := keysetCondition(page.After, []string{"created_at", "id"}, true, argCount)
+		query += ` AND ` + cond
+		args = append(args, keysetArgs...)
+		argCount += len(keysetArgs)
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+	limitClause, limitArgs := pageLimit(page, argCount)
+	query += limitClause
+	args = append(args, limitArgs...)
 
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -82,12 +95,17 @@ func (r *ListRepository) GetByUserID(userID int) ([]models.List, error) {
 		var descNull sql.NullString
 		err := rows.Scan(&list.ID, &list.UserID, &list.Name, &descNull, &list.Public, &list.CreatedAt, &list.UpdatedAt)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		list.Description = descNull.String
 		lists = append(lists, list)
 	}
-	return lists, nil
+	next := ""
+	if len(lists) > 0 {
+		last := lists[len(lists)-1]
+		next = nextCursor(page, len(lists), models.Cursor{Time: last.CreatedAt, ID: last.ID})
+	}
+	return lists, next, nil
 }
 
 func (r *ListRepository) Update(listID, userID int, name, description string, public bool) (*models.List, error) {
@@ -193,54 +211,84 @@ func (r *ListRepository) UnbookmarkList(userID, listID int) error {
 	return nil
 }
 
-func (r *ListRepository) GetBookmarkedLists(userID int) ([]models.List, error) {
-	query := `SELECT l.id, l.user_id, l.name, l.description, l.public, l.created_at, l.updated_at
+// GetBookmarkedLists returns the lists a user bookmarked, newest bookmark first, and the next page cursor.
+func (r *ListRepository) GetBookmarkedLists(userID int, page models.PageParams) ([]models.List, string, error) {
+	query := `SELECT l.id, l.user_id, l.name, l.description, l.public, l.created_at, l.updated_at, lb.created_at
 FROM lists l
 JOIN list_bookmarks lb on l.id = lb.list_id
-WHERE lb.user_id = $1
-ORDER BY lb.created_at DESC`
-	rows, err := r.db.Query(query, userID)
+WHERE lb.user_id = $1`
+	args := []interface{}{userID}
+	argCount := 2
+	if page.After != nil {
+		cond, keysetArgs := keysetCondition(page.After, []string{"lb.created_at", "l.id"}, true, argCount)
+		query += ` AND ` + cond
+		args = append(args, keysetArgs...)
+		argCount += len(keysetArgs)
+	}
+	query += ` ORDER BY lb.created_at DESC, l.id DESC`
+	limitClause, limitArgs := pageLimit(page, argCount)
+	query += limitClause
+	args = append(args, limitArgs...)
+
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 	lists := []models.List{}
+	var last models.Cursor
 	for rows.Next() {
 		var list models.List
 		var descNull sql.NullString
-		err := rows.Scan(&list.ID, &list.UserID, &list.Name, &descNull, &list.Public, &list.CreatedAt, &list.UpdatedAt)
+		err := rows.Scan(&list.ID, &list.UserID, &list.Name, &descNull, &list.Public, &list.CreatedAt, &list.UpdatedAt, &last.Time)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		list.Description = descNull.String
+		last.ID = list.ID
 		lists = append(lists, list)
 	}
-	return lists, nil
+	return lists, nextCursor(page, len(lists), last), nil
 }
 
-func (r *ListRepository) GetPopularLists(limit int) ([]models.List, error) {
+// GetPopularLists orders public lists by bookmarks and returns the next page cursor.
+func (r *ListRepository) GetPopularLists(page models.PageParams) ([]models.List, string, error) {
 	query := `SELECT l.id, l.user_id, l.name, l.description, l.public, l.created_at, l.updated_at, COUNT(lb.user_id) AS bookmark_count
 FROM lists l
 LEFT JOIN list_bookmarks lb on l.id = lb.list_id
 WHERE l.public = true
-GROUP BY l.id, l.user_id, l.name, l.description, l.public, l.created_at, l.updated_at
-ORDER BY bookmark_count DESC, l.created_at DESC LIMIT $1`
-	rows, err := r.db.Query(query, limit)
+GROUP BY l.id, l.user_id, l.name, l.description, l.public, l.created_at, l.updated_at`
+	args := []interface{}{}
+	argCount := 1
+	if page.After != nil {
+		cond, keysetArgs := keysetCondition(page.After, []string{"COUNT(lb.user_id)", "l.created_at", "l.id"}, true, argCount)
+		query += ` HAVING ` + cond
+		args = append(args, keysetArgs...)
+		argCount += len(keysetArgs)
+	}
+	query += ` ORDER BY bookmark_count DESC, l.created_at DESC, l.id DESC`
+	limitClause, limitArgs := pageLimit(page, argCount)
+	query += limitClause
+	args = append(args, limitArgs...)
+
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 	lists := []models.List{}
+	var last models.Cursor
 	for rows.Next() {
 		var list models.List
 		var descNull sql.NullString
 		var bookmarkCount int
 		err := rows.Scan(&list.ID, &list.UserID, &list.Name, &descNull, &list.Public, &list.CreatedAt, &list.UpdatedAt, &bookmarkCount)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		list.Description = descNull.String
 		lists = append(lists, list)
+		last = models.Cursor{Value: float64(bookmarkCount), Time: list.CreatedAt, ID: list.ID}
 	}
-	return lists, nil
+	return lists, nextCursor(page, len(lists), last), nil
 }
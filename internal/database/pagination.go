@@ -0,0 +1,52 @@
+package database
+
+import (
+	"fmt"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+	"strings"
+)
+
+// keysetCondition restricts a query to the rows after the cursor. cols are the
+// ORDER BY expressions ending in the timestamp and id, optionally led by a
+// numeric column matched against the cursor's Value. Every column must be
+// sorted in the same direction.
+func keysetCondition(after *models.Cursor, cols []string, desc bool, argCount int) (string, []interface{}) {
+	args := []interface{}{after.Time, after.ID}
+	if len(cols) == 3 {
+		args = append([]interface{}{after.Value}, args...)
+	}
+	placeholders := make([]string, len(args))
+	for i := range args {
+		placeholders[i] = fmt.Sprintf("$%d", argCount+i)
+	}
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s) %s (%s)", strings.Join(cols, ", "), op, strings.Join(placeholders, ", ")), args
+}
+
+// pageLimit returns the LIMIT/OFFSET suffix for a page. A cursor replaces the offset.
+func pageLimit(page models.PageParams, argCount int) (string, []interface{}) {
+	clause := ""
+	args := []interface{}{}
+	if page.Limit > 0 {
+		clause += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, page.Limit)
+		argCount++
+	}
+	if page.After == nil && page.Offset > 0 {
+		clause += fmt.Sprintf(" OFFSET $%d", argCount)
+		args = append(args, page.Offset)
+	}
+	return clause, args
+}
+
+// nextCursor returns the token for the following page, or "" when the page
+// was not full and there is nothing more to fetch.
+func nextCursor(page models.PageParams, count int, last models.Cursor) string {
+	if page.Limit == 0 || count < page.Limit {
+		return ""
+	}
+	return last.Encode()
+}
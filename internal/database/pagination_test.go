@@ -0,0 +1,96 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+func TestKeysetCondition(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC)
+	tests := []struct {
+		name     string
+		after    models.Cursor
+		cols     []string
+		desc     bool
+		argCount int
+		wantCond string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "time and id descending",
+			after:    models.Cursor{Time: at, ID: 7},
+			cols:     []string{"r.created_at", "r.id"},
+			desc:     true,
+			argCount: 2,
+			wantCond: "(r.created_at, r.id) < ($2, $3)",
+			wantArgs: []interface{}{at, 7},
+		},
+		{
+			name:     "value leads ascending",
+			after:    models.Cursor{Value: 0.0607927, Time: at, ID: 3},
+			cols:     []string{"rank", "b.created_at", "b.id"},
+			desc:     false,
+			argCount: 5,
+			wantCond: "(rank, b.created_at, b.id) > ($5, $6, $7)",
+			wantArgs: []interface{}{0.0607927, at, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond, args := keysetCondition(&tt.after, tt.cols, tt.desc, tt.argCount)
+			if cond != tt.wantCond {
+				t.Errorf("cond = %q, want %q", cond, tt.wantCond)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestPageLimit(t *testing.T) {
+	after := &models.Cursor{ID: 1}
+	tests := []struct {
+		name       string
+		page       models.PageParams
+		wantClause string
+		wantArgs   []interface{}
+	}{
+		{"everything", models.PageParams{}, "", []interface{}{}},
+		{"limit only", models.PageParams{Limit: 20}, " LIMIT $4", []interface{}{20}},
+		{"limit and offset", models.PageParams{Limit: 20, Offset: 40}, " LIMIT $4 OFFSET $5", []interface{}{20, 40}},
+		{"cursor replaces offset", models.PageParams{Limit: 20, Offset: 40, After: after}, " LIMIT $4", []interface{}{20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause, args := pageLimit(tt.page, 4)
+			if clause != tt.wantClause {
+				t.Errorf("clause = %q, want %q", clause, tt.wantClause)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	last := models.Cursor{Time: time.Unix(1700000000, 0).UTC(), ID: 9}
+	if got := nextCursor(models.PageParams{}, 50, last); got != "" {
+		t.Errorf("unlimited page returned cursor %q", got)
+	}
+	if got := nextCursor(models.PageParams{Limit: 20}, 19, last); got != "" {
+		t.Errorf("short page returned cursor %q", got)
+	}
+	got := nextCursor(models.PageParams{Limit: 20}, 20, last)
+	decoded, err := models.DecodeCursor(got)
+	if err != nil {
+		t.Fatalf("full page cursor %q: %v", got, err)
+	}
+	if *decoded != last {
+		t.Errorf("decoded %+v, want %+v", *decoded, last)
+	}
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+type PendingEditRepository struct {
+	db *sql.DB
+}
+
+func NewPendingEditRepository(db *sql.DB) *PendingEditRepository {
+	return &PendingEditRepository{db: db}
+}
+
+// Submit queues a catalog change for review. changes is nil for delete requests.
+func (r *PendingEditRepository) Submit(bookID, userID int, action string, changes *models.UpdateBookRequest) (*models.PendingBookEdit, error) {
+	// A nil []byte would reach Postgres as '', which isn't valid JSONB
+	var changesJSON interface{}
+	if changes != nil {
+		data, err := json.Marshal(changes)
+		if err != nil {
+			return nil, err
+		}
+		changesJSON = string(data)
+	}
+
+	query := `
+		INSERT INTO pending_book_edits (book_id, user_id, action, changes)
+		SELECT b.id, $2, $3, $4
+		FROM books b
+		WHERE b.id = $1
+		RETURNING id, status, created_at`
+
+	edit := &models.PendingBookEdit{
+		BookID:  bookID,
+		UserID:  userID,
+		Action:  action,
+		Changes: changes,
+	}
+	err := r.db.QueryRow(query, bookID, userID, action, changesJSON).Scan(&edit.ID, &edit.Status, &edit.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("book not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return edit, nil
+}
+
+const pendingEditSelect = `
+	SELECT e.id, e.book_id, b.title, e.user_id, u.username, e.action, e.changes,
+	       e.status, e.reviewed_by, e.reviewed_at, e.created_at
+	FROM pending_book_edits e
+	JOIN books b ON b.id = e.book_id
+	JOIN users u ON u.id = e.user_id`
+
+func (r *PendingEditRepository) List(status string, limit, offset int) ([]*models.PendingBookEdit, error) {
+	query := pendingEditSelect + `
+	WHERE e.status = $1
+	ORDER BY e.created_at ASC
+	LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []*models.PendingBookEdit{}
+	for rows.Next() {
+		edit, err := scanPendingEdit(rows)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+	return edits, rows.Err()
+}
+
+func (r *PendingEditRepository) GetByID(id int) (*models.PendingBookEdit, error) {
+	return scanPendingEdit(r.db.QueryRow(pendingEditSelect+` WHERE e.id = $1`, id))
+}
+
+// MarkReviewed moves a pending edit to approved or rejected. It returns
+// sql.ErrNoRows if the edit doesn't exist or was already reviewed.
+func (r *PendingEditRepository) MarkReviewed(id, reviewerID int, status string) error {
+	query := `
+		UPDATE pending_book_edits
+		SET status = $1, reviewed_by = $2, reviewed_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND status = 'pending'`
+
+	result, err := r.db.Exec(query, status, reviewerID, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CoverInUse reports whether a book, a book's history or an edit still
+// waiting for review refers to coverURL.
+func (r *PendingEditRepository) CoverInUse(coverURL string) (bool, error) {
+	query := `SELECT
+		EXISTS(SELECT 1 FROM books WHERE cover_url = $1)
+		OR EXISTS(SELECT 1 FROM pending_book_edits WHERE status = 'pending' AND changes->>'cover_url' = $1)
+		OR EXISTS(SELECT 1 FROM book_revisions
+			WHERE changes->'cover_url'->>'old' = $1 OR changes->'cover_url'->>'new' = $1)`
+	var inUse bool
+	err := r.db.QueryRow(query, coverURL).Scan(&inUse)
+	return inUse, err
+}
+
+// Reopen puts a claimed edit back in the queue when applying it failed.
+func (r *PendingEditRepository) Reopen(id int) error {
+	query := `
+		UPDATE pending_book_edits
+		SET status = 'pending', reviewed_by = NULL, reviewed_at = NULL
+		WHERE id = $1`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPendingEdit(row rowScanner) (*models.PendingBookEdit, error) {
+	edit := &models.PendingBookEdit{}
+	var changes []byte
+	var reviewedBy sql.NullInt64
+	var reviewedAt sql.NullTime
+	err := row.Scan(
+		&edit.ID,
+		&edit.BookID,
+		&edit.BookTitle,
+		&edit.UserID,
+		&edit.Username,
+		&edit.Action,
+		&changes,
+		&edit.Status,
+		&reviewedBy,
+		&reviewedAt,
+		&edit.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) > 0 {
+		edit.Changes = &models.UpdateBookRequest{}
+		if err := json.Unmarshal(changes, edit.Changes); err != nil {
+			return nil, err
+		}
+	}
+	if reviewedBy.Valid {
+		id := int(reviewedBy.Int64)
+		edit.ReviewedBy = &id
+	}
+	if reviewedAt.Valid {
+		edit.ReviewedAt = &reviewedAt.Time
+	}
+	return edit, nil
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pulkyeet/BookmarkD/internal/markdown"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+type ProgressRepository struct {
+	db *sql.DB
+}
+
+func NewProgressRepository(db *sql.DB) *ProgressRepository {
+	return &ProgressRepository{db: db}
+}
+
+const progressSelect = `SELECT p.id, p.session_id, r.book_id, p.page, p.percent, b.page_count, p.comment, p.created_at
+FROM reading_progress p
+JOIN reading_sessions s ON s.id = p.session_id
+JOIN ratings r ON r.id = s.rating_id
+JOIN books b ON b.id = r.book_id`
+
+func scanProgress(row rowScanner) (*models.ReadingProgress, error) {
+	p := &models.ReadingProgress{}
+	var page, pageCount sql.NullInt64
+	var percent sql.NullFloat64
+	var comment sql.NullString
+	err := row.Scan(&p.ID, &p.SessionID, &p.BookID, &page, &percent, &pageCount, &comment, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if page.Valid {
+		pg := int(page.Int64)
+		p.Page = &pg
+	}
+	if percent.Valid {
+		p.Percent = &percent.Float64
+	}
+	p.PageCount = int(pageCount.Int64)
+	p.Comment = comment.String
+	p.CommentHTML = markdown.Render(p.Comment)
+	return p, nil
+}
+
+// Add records a progress update on the user's read in progress. Posting
+// progress on a book that isn't being read starts a new read.
+func (r *ProgressRepository) Add(userID, bookID int, page *int, percent *float64, comment string) (*models.ReadingProgress, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var pageCount sql.NullInt64
+	err = tx.QueryRow(`SELECT page_count FROM books WHERE id = $1`, bookID).Scan(&pageCount)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("book not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	page, percent, err = models.ResolveProgress(page, percent, int(pageCount.Int64))
+	if err != nil {
+		return nil, err
+	}
+
+	var ratingID int
+	var prevStatus string
+	err = tx.QueryRow(`
+		INSERT INTO ratings (user_id, book_id, rating, status)
+		VALUES ($1, $2, 0, 'to_read')
+		ON CONFLICT (user_id, book_id) DO UPDATE SET updated_at = ratings.updated_at
+		RETURNING id, status`, userID, bookID).Scan(&ratingID, &prevStatus)
+	if err != nil {
+		return nil, err
+	}
+	if prevStatus != "currently_reading" {
+		if err := syncReadingSession(tx, ratingID, prevStatus, "currently_reading", 0, models.ReadDates{}); err != nil {
+			return nil, err
+		}
+		if err := refreshEntry(tx, ratingID, true); err != nil {
+			return nil, err
+		}
+	}
+
+	var progressID int
+	err = tx.QueryRow(`
+		INSERT INTO reading_progress (session_id, page, percent, comment)
+		SELECT id, $2, $3, $4 FROM reading_sessions
+		WHERE rating_id = $1 AND NOT finished AND stopped_at IS NULL
+		RETURNING id`, ratingID, page, percent, nullString(comment)).Scan(&progressID)
+	if err != nil {
+		return nil, err
+	}
+
+	progress, err := scanProgress(tx.QueryRow(progressSelect+` WHERE p.id = $1`, progressID))
+	if err != nil {
+		return nil, err
+	}
+	return progress, tx.Commit()
+}
+
+// ListForBook returns the user's progress updates on a book across all their
+// reads, newest first.
+func (r *ProgressRepository) ListForBook(userID, bookID int) ([]models.ReadingProgress, error) {
+	query := progressSelect + `
+WHERE r.user_id = $1 AND r.book_id = $2
+ORDER BY p.created_at DESC, p.id DESC`
+	rows, err := r.db.Query(query, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	updates := []models.ReadingProgress{}
+	for rows.Next() {
+		p, err := scanProgress(rows)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, *p)
+	}
+	return updates, rows.Err()
+}
+
+// Delete removes one of the user's progress updates and returns the book it was for.
+func (r *ProgressRepository) Delete(progressID, userID int) (int, error) {
+	var bookID int
+	err := r.db.QueryRow(`
+		DELETE FROM reading_progress p
+		USING reading_sessions s, ratings r
+		WHERE p.id = $1 AND s.id = p.session_id AND r.id = s.rating_id AND r.user_id = $2
+		RETURNING r.book_id`, progressID, userID).Scan(&bookID)
+	return bookID, err
+}
+
+// currentReads lists the books a user is reading with their latest progress,
+// most recently active first, leaving out entries the viewer can't see.
+func currentReads(db *sql.DB, userID int, viewerID *int) ([]models.CurrentRead, error) {
+	visible := visibleTo("")
+	args := []interface{}{userID}
+	if viewerID != nil {
+		visible = visibleTo("$2")
+		args = append(args, *viewerID)
+	}
+	rows, err := db.Query(`
+		SELECT b.id, b.title, b.author, b.cover_url, b.page_count, s.id, s.started_at,
+			p.id, p.page, p.percent, p.comment, p.created_at
+		FROM ratings r
+		JOIN reading_sessions s ON s.rating_id = r.id AND NOT s.finished AND s.stopped_at IS NULL
+		JOIN books b ON b.id = r.book_id
+		LEFT JOIN LATERAL (
+			SELECT id, page, percent, comment, created_at
+			FROM reading_progress
+			WHERE session_id = s.id
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		) p ON true
+		WHERE r.user_id = $1 AND `+visible+`
+		ORDER BY COALESCE(p.created_at, s.created_at) DESC, s.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reads := []models.CurrentRead{}
+	for rows.Next() {
+		var read models.CurrentRead
+		var sessionID int
+		var coverURL, comment sql.NullString
+		var pageCount, progressID, page sql.NullInt64
+		var percent sql.NullFloat64
+		var startedAt, progressAt sql.NullTime
+		err := rows.Scan(&read.BookID, &read.Title, &read.Author, &coverURL, &pageCount, &sessionID, &startedAt,
+			&progressID, &page, &percent, &comment, &progressAt)
+		if err != nil {
+			return nil, err
+		}
+		read.CoverURL = coverURL.String
+		read.PageCount = int(pageCount.Int64)
+		if startedAt.Valid {
+			read.StartedAt = &startedAt.Time
+		}
+		if progressID.Valid {
+			read.Progress = &models.ReadingProgress{
+				ID:        int(progressID.Int64),
+				SessionID: sessionID,
+				BookID:    read.BookID,
+				PageCount: read.PageCount,
+				Comment:   comment.String,
+				CreatedAt: progressAt.Time,
+			}
+			if page.Valid {
+				pg := int(page.Int64)
+				read.Progress.Page = &pg
+			}
+			if percent.Valid {
+				read.Progress.Percent = &percent.Float64
+			}
+		}
+		reads = append(reads, read)
+	}
+	return reads, rows.Err()
+}
@@ -3,7 +3,10 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
+	"github.com/lib/pq"
+	"github.com/pulkyeet/BookmarkD/internal/markdown"
 	"github.com/pulkyeet/BookmarkD/internal/models"
 )
 
@@ -15,47 +18,210 @@ func NewRatingRepository(db *sql.DB) *RatingRepository {
 	return &RatingRepository{db: db}
 }
 
-// Create or update rating
-func (r *RatingRepository) Upsert(userID, bookID, rating int, review string, status string) (*models.Rating, error) {
+// Upsert creates or updates the user's entry for a book. Status changes open
+// and close reading sessions, dated with the given dates or today, and the
+// entry's rating always follows the latest finished read, so starting a re-read
+// keeps the previous rating. The entry keeps its id, and with it its likes and
+// comments, and a replaced review is kept as a revision. language is the
+// review's normalized language, if known.
+func (r *RatingRepository) Upsert(userID, bookID, rating int, review string, spoiler bool, language, status string, privacy models.EntryPrivacy, dates models.ReadDates) (*models.Rating, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var prevID int
+	var prevStatus string
+	err = tx.QueryRow(`SELECT id, status FROM ratings WHERE user_id = $1 AND book_id = $2 FOR UPDATE`, userID, bookID).Scan(&prevID, &prevStatus)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == nil {
+		if err := saveReviewRevision(tx, prevID, review); err != nil {
+			return nil, err
+		}
+	}
+
 	query := `
-INSERT INTO ratings (user_id, book_id, rating, review, status)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO ratings (user_id, book_id, rating, review, review_html, review_is_spoiler, status, visibility, private_notes, review_language)
+VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8::entry_visibility, 'public'), $9::text, $11)
 ON CONFLICT (user_id, book_id)
 DO UPDATE SET
 	rating = EXCLUDED.rating,
 	review = EXCLUDED.review,
+	review_html = EXCLUDED.review_html,
+	review_is_spoiler = EXCLUDED.review_is_spoiler,
+	review_language = EXCLUDED.review_language,
 	status = EXCLUDED.status,
+	visibility = COALESCE($8::entry_visibility, ratings.visibility),
+	private_notes = CASE WHEN $10::boolean THEN $9::text ELSE ratings.private_notes END,
 	updated_at = CURRENT_TIMESTAMP
-RETURNING id, user_id, book_id, rating, review, status, created_at, updated_at`
+RETURNING id`
+
+	var ratingID int
+	if err := tx.QueryRow(query, userID, bookID, rating, nullString(review), nullString(markdown.Render(review)), spoiler, status,
+		nullString(privacy.Visibility), privateNotesArg(privacy), privacy.PrivateNotes != nil, reviewLanguageArg(review, language)).Scan(&ratingID); err != nil {
+		return nil, err
+	}
+	if err := syncReadingSession(tx, ratingID, prevStatus, status, rating, dates); err != nil {
+		return nil, err
+	}
+	if err := refreshEntry(tx, ratingID, false); err != nil {
+		return nil, err
+	}
+
+	ratingModel, err := scanRatingEntry(tx.QueryRow(ratingEntrySelect+` WHERE r.id = $1`, ratingID))
+	if err != nil {
+		return nil, err
+	}
+	return ratingModel, tx.Commit()
+}
+
+// reviewLanguageArg stores a language only alongside review text.
+func reviewLanguageArg(review, language string) interface{} {
+	if strings.TrimSpace(review) == "" {
+		return nil
+	}
+	return nullString(language)
+}
+
+func privateNotesArg(privacy models.EntryPrivacy) interface{} {
+	if privacy.PrivateNotes == nil {
+		return nil
+	}
+	return nullString(*privacy.PrivateNotes)
+}
+
+// saveReviewRevision keeps the entry's review as a revision and marks the
+// entry edited if the review is about to be replaced with different text.
+// Filling in a review for the first time isn't an edit.
+func saveReviewRevision(tx *sql.Tx, ratingID int, review string) error {
+	_, err := tx.Exec(`
+		WITH prev AS (
+			INSERT INTO review_revisions (rating_id, review, review_html, review_is_spoiler, written_at)
+			SELECT id, review, review_html, review_is_spoiler, COALESCE(edited_at, created_at)
+			FROM ratings
+			WHERE id = $1 AND COALESCE(review, '') <> '' AND review IS DISTINCT FROM $2
+			RETURNING rating_id
+		)
+		UPDATE ratings SET edited_at = CURRENT_TIMESTAMP WHERE id IN (SELECT rating_id FROM prev)`,
+		ratingID, nullString(review))
+	return err
+}
 
-	ratingModel := &models.Rating{}
-	var reviewNull sql.NullString
+// ratingEntrySelect reads a user's entry for a book along with how many times
+// they have finished it and the dates of the current read.
+const ratingEntrySelect = `SELECT r.id, r.user_id, r.book_id, r.rating, r.review, r.review_html, r.review_is_spoiler, r.review_language, r.edited_at, r.status, r.visibility, r.private_notes, r.created_at, r.updated_at, ` + readCountExpr + `,
+	cur.started_at, cur.finished_at, cur.stopped_at, cur.stopped_at_page
+FROM ratings r
+LEFT JOIN LATERAL (
+	SELECT s.started_at, s.finished_at, s.stopped_at, s.stopped_at_page FROM reading_sessions s
+	WHERE s.rating_id = r.id
+	ORDER BY NOT s.finished AND s.stopped_at IS NULL DESC, COALESCE(s.finished_at, s.stopped_at) DESC NULLS LAST, s.id DESC
+	LIMIT 1
+) cur ON true`
 
-	err := r.db.QueryRow(query, userID, bookID, rating, nullString(review), status).Scan(
-		&ratingModel.ID,
-		&ratingModel.UserID,
-		&ratingModel.BookID,
-		&ratingModel.Rating,
+func scanRatingEntry(row rowScanner) (*models.Rating, error) {
+	rating := &models.Rating{}
+	var reviewNull, reviewHTMLNull, languageNull, notesNull sql.NullString
+	var editedAt, startedAt, finishedAt, stoppedAt sql.NullTime
+	var stoppedAtPage sql.NullInt64
+	err := row.Scan(
+		&rating.ID,
+		&rating.UserID,
+		&rating.BookID,
+		&rating.Rating,
 		&reviewNull,
-		&ratingModel.Status,
-		&ratingModel.CreatedAt,
-		&ratingModel.UpdatedAt,
+		&reviewHTMLNull,
+		&rating.Spoiler,
+		&languageNull,
+		&editedAt,
+		&rating.Status,
+		&rating.Visibility,
+		&notesNull,
+		&rating.CreatedAt,
+		&rating.UpdatedAt,
+		&rating.ReadCount,
+		&startedAt,
+		&finishedAt,
+		&stoppedAt,
+		&stoppedAtPage,
 	)
 	if err != nil {
 		return nil, err
 	}
-	ratingModel.Review = reviewNull.String
-	return ratingModel, nil
+	rating.Review = reviewNull.String
+	rating.ReviewHTML = renderedHTML(reviewHTMLNull, rating.Review)
+	rating.ReviewSegments = models.ParseReview(rating.Review, rating.Spoiler)
+	rating.Language = languageNull.String
+	rating.PrivateNotes = notesNull.String
+	if editedAt.Valid {
+		rating.EditedAt = &editedAt.Time
+	}
+	if startedAt.Valid {
+		rating.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		rating.FinishedAt = &finishedAt.Time
+	}
+	if stoppedAt.Valid {
+		rating.StoppedAt = &stoppedAt.Time
+	}
+	if stoppedAtPage.Valid {
+		page := int(stoppedAtPage.Int64)
+		rating.StoppedAtPage = &page
+	}
+	return rating, nil
+}
+
+// sameWorkBookIDs selects every edition that shares a work with book $1.
+const sameWorkBookIDs = `SELECT id FROM books WHERE work_id = (SELECT work_id FROM books WHERE id = $1)`
+
+// renderedHTML returns the stored HTML for Markdown source, rendering it for
+// rows saved before HTML was stored.
+func renderedHTML(stored sql.NullString, source string) string {
+	if stored.Valid || source == "" {
+		return stored.String
+	}
+	return markdown.Render(source)
 }
 
-// Get all ratings for a book with stats
-func (r *RatingRepository) GetByBookID(bookID int, userID *int, sortBy string) (*models.BookRatingStats, error) {
+// visibleTo restricts entries aliased r to those the viewer in parameter
+// viewerParam may see: public entries, their own, and followers-only entries
+// of people they follow. An empty viewerParam is a logged-out viewer, who sees
+// only public entries.
+func visibleTo(viewerParam string) string {
+	if viewerParam == "" {
+		return `r.visibility = 'public'`
+	}
+	return `(r.visibility = 'public' OR r.user_id = ` + viewerParam + ` OR (r.visibility = 'followers' AND EXISTS(
+		SELECT 1 FROM follows WHERE follower_id = ` + viewerParam + ` AND following_id = r.user_id)))`
+}
+
+// spoilersRevealedExpr is true when the viewer in parameter userParam has asked
+// to see spoilers for books they've finished, and has finished some edition of
+// bookExpr's work.
+func spoilersRevealedExpr(userParam, bookExpr string) string {
+	return `(SELECT vu.reveal_finished_spoilers FROM users vu WHERE vu.id = ` + userParam + `) AND EXISTS(
+		SELECT 1 FROM reading_sessions vs
+		JOIN ratings vr ON vr.id = vs.rating_id
+		WHERE vr.user_id = ` + userParam + ` AND vs.finished
+			AND vr.book_id IN (SELECT id FROM books WHERE work_id = (SELECT work_id FROM books WHERE id = ` + bookExpr + `)))`
+}
+
+// GetByBookID returns rating stats rolled up across every edition of the work,
+// plus a page of the ratings the user may see that match filter, in the
+// requested order. Entries with neither a rating nor a review, such as a bare
+// to_read, aren't listed. The stats count every rating, private ones
+// included, as they name no one; unrated entries are left out of them.
+func (r *RatingRepository) GetByBookID(bookID int, userID *int, sortBy string, filter models.ReviewFilter, page models.PageParams) (*models.BookRatingStats, error) {
 	statsQuery := `
 SELECT
 	COALESCE(AVG(rating), 0) AS average_rating,
 	COUNT(*) as total
 FROM ratings
-WHERE book_id = $1`
+WHERE book_id IN (` + sameWorkBookIDs + `) AND rating > 0`
 
 	stats := &models.BookRatingStats{BookID: bookID}
 	err := r.db.QueryRow(statsQuery, bookID).Scan(&stats.AverageRating, &stats.TotalRatings)
@@ -63,16 +229,23 @@ WHERE book_id = $1`
 		return nil, err
 	}
 
+	viewerVisible := visibleTo("")
+	if userID != nil {
+		viewerVisible = visibleTo("$2")
+	}
+
 	ratingsQuery := `
 SELECT
-	r.id, r.user_id, r.book_id, r.rating, r.review, r.created_at, r.updated_at,
+	r.id, r.user_id, r.book_id, r.rating, r.review, r.review_html, r.review_is_spoiler, r.review_language, r.edited_at, r.created_at, r.updated_at,
 	u.username,
 	COUNT(DISTINCT rl.user_id) as like_count,
-	COUNT(DISTINCT c.id) as comment_count`
+	COUNT(DISTINCT c.id) as comment_count,
+	` + readCountExpr + ` as read_count`
 
 	if userID != nil {
 		ratingsQuery += `,
-	EXISTS(SELECT 1 FROM review_likes WHERE user_id = $2 AND rating_id = r.id) as liked_by_user`
+	EXISTS(SELECT 1 FROM review_likes WHERE user_id = $2 AND rating_id = r.id) as liked_by_user,
+	` + spoilersRevealedExpr("$2", "$1") + ` as spoilers_revealed`
 	}
 
 	ratingsQuery += `
@@ -80,26 +253,67 @@ FROM ratings r
 JOIN users u ON r.user_id = u.id
 LEFT JOIN review_likes rl ON r.id = rl.rating_id
 LEFT JOIN comments c ON r.id = c.rating_id
-WHERE r.book_id = $1
-GROUP BY r.id, r.user_id, r.book_id, r.rating, r.review, r.created_at, r.updated_at, u.username`
+WHERE r.book_id IN (` + sameWorkBookIDs + `) AND ` + viewerVisible + `
+	AND (r.rating > 0 OR COALESCE(BTRIM(r.review), '') <> '')`
 
+	args := []interface{}{bookID}
+	if userID != nil {
+		args = append(args, *userID)
+	}
+	argCount := len(args) + 1
+
+	if filter.HasReview {
+		ratingsQuery += ` AND COALESCE(BTRIM(r.review), '') <> ''`
+	}
+	if filter.MinRating > 0 {
+		ratingsQuery += fmt.Sprintf(` AND r.rating >= $%d`, argCount)
+		args = append(args, filter.MinRating)
+		argCount++
+	}
+	if filter.MaxRating > 0 {
+		ratingsQuery += fmt.Sprintf(` AND r.rating <= $%d`, argCount)
+		args = append(args, filter.MaxRating)
+		argCount++
+	}
+	if filter.Following && userID != nil {
+		ratingsQuery += ` AND r.user_id IN (SELECT following_id FROM follows WHERE follower_id = $2)`
+	}
+	if filter.Language != "" {
+		ratingsQuery += fmt.Sprintf(` AND r.review_language = $%d`, argCount)
+		args = append(args, filter.Language)
+		argCount++
+	}
+	ratingsQuery += `
+GROUP BY r.id, r.user_id, r.book_id, r.rating, r.review, r.review_html, r.review_is_spoiler, r.review_language, r.edited_at, r.created_at, r.updated_at, u.username`
+
+	// like_count is an aggregate, so the cursor condition goes in HAVING
+	var keyset []string
+	var orderBy string
 	switch sortBy {
 	case "most_liked":
-		ratingsQuery += ` ORDER BY like_count DESC, r.created_at DESC`
+		keyset = []string{"COUNT(DISTINCT rl.user_id)", "r.created_at", "r.id"}
+		orderBy = ` ORDER BY like_count DESC, r.created_at DESC, r.id DESC`
 	case "highest_rating":
-		ratingsQuery += ` ORDER BY r.rating DESC, r.created_at DESC`
+		keyset = []string{"r.rating", "r.created_at", "r.id"}
+		orderBy = ` ORDER BY r.rating DESC, r.created_at DESC, r.id DESC`
 	case "newest":
 		fallthrough
 	default:
-		ratingsQuery += ` ORDER BY r.created_at DESC`
+		keyset = []string{"r.created_at", "r.id"}
+		orderBy = ` ORDER BY r.created_at DESC, r.id DESC`
 	}
-
-	var rows *sql.Rows
-	if userID != nil {
-		rows, err = r.db.Query(ratingsQuery, bookID, *userID)
-	} else {
-		rows, err = r.db.Query(ratingsQuery, bookID)
+	if page.After != nil {
+		cond, keysetArgs := keysetCondition(page.After, keyset, true, argCount)
+		ratingsQuery += ` HAVING ` + cond
+		args = append(args, keysetArgs...)
+		argCount += len(keysetArgs)
 	}
+	ratingsQuery += orderBy
+	limitClause, limitArgs := pageLimit(page, argCount)
+	ratingsQuery += limitClause
+	args = append(args, limitArgs...)
+
+	rows, err := r.db.Query(ratingsQuery, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -108,23 +322,25 @@ GROUP BY r.id, r.user_id, r.book_id, r.rating, r.review, r.created_at, r.updated
 	ratings := []models.RatingWithLikes{}
 	for rows.Next() {
 		var rating models.RatingWithLikes
-		var reviewNull sql.NullString
+		var reviewNull, reviewHTMLNull, languageNull sql.NullString
+		var editedAt sql.NullTime
 		var ratingValue int64
 
 		if userID != nil {
 			err := rows.Scan(
-				&rating.ID, &rating.UserID, &rating.BookID, &ratingValue, &reviewNull,
+				&rating.ID, &rating.UserID, &rating.BookID, &ratingValue, &reviewNull, &reviewHTMLNull, &rating.Spoiler, &languageNull, &editedAt,
 				&rating.CreatedAt, &rating.UpdatedAt, &rating.Username,
-				&rating.LikeCount, &rating.CommentCount, &rating.LikedByUser,
+				&rating.LikeCount, &rating.CommentCount, &rating.ReadCount, &rating.LikedByUser,
+				&rating.SpoilersRevealed,
 			)
 			if err != nil {
 				return nil, err
 			}
 		} else {
 			err := rows.Scan(
-				&rating.ID, &rating.UserID, &rating.BookID, &ratingValue, &reviewNull,
+				&rating.ID, &rating.UserID, &rating.BookID, &ratingValue, &reviewNull, &reviewHTMLNull, &rating.Spoiler, &languageNull, &editedAt,
 				&rating.CreatedAt, &rating.UpdatedAt, &rating.Username,
-				&rating.LikeCount, &rating.CommentCount,
+				&rating.LikeCount, &rating.CommentCount, &rating.ReadCount,
 			)
 			if err != nil {
 				return nil, err
@@ -134,37 +350,83 @@ GROUP BY r.id, r.user_id, r.book_id, r.rating, r.review, r.created_at, r.updated
 
 		rating.Rating.Rating = int(ratingValue)
 		rating.Review = reviewNull.String
+		rating.Language = languageNull.String
+		rating.ReviewHTML = renderedHTML(reviewHTMLNull, rating.Review)
+		rating.ReviewSegments = models.ParseReview(rating.Review, rating.Spoiler)
+		if editedAt.Valid {
+			rating.EditedAt = &editedAt.Time
+		}
 		ratings = append(ratings, rating)
 	}
 	stats.Ratings = ratings
+	if len(ratings) > 0 {
+		last := ratings[len(ratings)-1]
+		cursor := models.Cursor{Time: last.CreatedAt, ID: last.ID}
+		switch sortBy {
+		case "most_liked":
+			cursor.Value = float64(last.LikeCount)
+		case "highest_rating":
+			cursor.Value = float64(last.Rating.Rating)
+		}
+		stats.NextCursor = nextCursor(page, len(ratings), cursor)
+	}
 	return stats, nil
 }
 
-func (r *RatingRepository) GetByUserAndBook(userID, bookID int) (*models.Rating, error) {
-	query := `
-SELECT id, user_id, book_id, rating, review, status, created_at, updated_at
+// GetHistogram counts a book's ratings by value and its entries by status,
+// across every edition of the work. It returns sql.ErrNoRows if the book
+// doesn't exist.
+func (r *RatingRepository) GetHistogram(bookID int) (*models.RatingHistogram, error) {
+	var exists bool
+	if err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM books WHERE id = $1)`, bookID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	histogram := &models.RatingHistogram{BookID: bookID}
+	rows, err := r.db.Query(`SELECT rating, COUNT(*) FROM ratings
+WHERE book_id IN (`+sameWorkBookIDs+`) AND rating BETWEEN 1 AND 10
+GROUP BY rating`, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rating, count int
+		if err := rows.Scan(&rating, &count); err != nil {
+			return nil, err
+		}
+		histogram.Distribution[rating-1] = count
+		histogram.TotalRatings += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := &histogram.Statuses
+	err = r.db.QueryRow(`SELECT
+	COUNT(*) FILTER (WHERE status = 'to_read'),
+	COUNT(*) FILTER (WHERE status = 'currently_reading'),
+	COUNT(*) FILTER (WHERE status = 'finished_reading'),
+	COUNT(*) FILTER (WHERE status = 'did_not_finish')
 FROM ratings
-WHERE user_id = $1 AND book_id = $2`
-	rating := &models.Rating{}
-	var reviewNull sql.NullString
+WHERE book_id IN (`+sameWorkBookIDs+`)`, bookID).Scan(&statuses.ToRead, &statuses.CurrentlyReading, &statuses.FinishedReading, &statuses.DidNotFinish)
+	if err != nil {
+		return nil, err
+	}
+	return histogram, nil
+}
 
-	err := r.db.QueryRow(query, userID, bookID).Scan(
-		&rating.ID,
-		&rating.UserID,
-		&rating.BookID,
-		&rating.Rating,
-		&reviewNull,
-		&rating.Status,
-		&rating.CreatedAt,
-		&rating.UpdatedAt,
-	)
+func (r *RatingRepository) GetByUserAndBook(userID, bookID int) (*models.Rating, error) {
+	rating, err := scanRatingEntry(r.db.QueryRow(ratingEntrySelect+` WHERE r.user_id = $1 AND r.book_id = $2`, userID, bookID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	rating.Review = reviewNull.String
 	return rating, nil
 }
 
@@ -221,121 +483,190 @@ WHERE user_id = $1 AND book_id = $2`
 	return nil
 }
 
-func (r *RatingRepository) GetFeedByType(requestingUserID *int, feedType string, limit, offset int) ([]models.FeedItem, error) {
-	query := `
-    SELECT 
-        r.id, r.user_id, r.book_id, r.rating, r.review, r.status, r.created_at, r.updated_at,
-        u.username,
-        b.title, b.author, b.cover_url,
-        COUNT(DISTINCT rl.user_id) as like_count,
-        COUNT(DISTINCT c.id) as comment_count`
-
-	if requestingUserID != nil {
-		query += `,
-        EXISTS(SELECT 1 FROM review_likes WHERE user_id = $1 AND rating_id = r.id) as liked_by_user`
-	}
-
-	query += `
-    FROM ratings r
-    JOIN users u ON r.user_id = u.id
-    JOIN books b ON r.book_id = b.id
-    LEFT JOIN review_likes rl ON r.id = rl.rating_id
-    LEFT JOIN comments c ON r.id = c.rating_id`
-
+// GetFeedByType returns feed items newest first along with the cursor for the next page.
+func (r *RatingRepository) GetFeedByType(requestingUserID *int, feedType string, page models.PageParams) ([]models.FeedItem, string, error) {
+	likedExpr := `false`
+	revealedExpr := `false`
 	args := []interface{}{}
 	argCount := 1
-
-	// Apply following filter only if feedType is "following" AND user is logged in
-	if feedType == "following" && requestingUserID != nil {
-		query += ` WHERE r.user_id IN (SELECT following_id FROM follows WHERE follower_id = $` + fmt.Sprintf("%d", argCount) + `)`
+	if requestingUserID != nil {
+		likedExpr = `EXISTS(SELECT 1 FROM review_likes WHERE user_id = $1 AND rating_id = r.id)`
+		revealedExpr = spoilersRevealedExpr("$1", "r.book_id")
 		args = append(args, *requestingUserID)
 		argCount++
 	}
+	// Only entries the viewer may see. Apply following filter only if feedType
+	// is "following" AND user is logged in
+	entryFilter := ` AND ` + visibleTo("")
+	if requestingUserID != nil {
+		entryFilter = ` AND ` + visibleTo("$1")
+	}
+	if feedType == "following" && requestingUserID != nil {
+		entryFilter += ` AND r.user_id IN (SELECT following_id FROM follows WHERE follower_id = $1)`
+	}
 
-	query += ` GROUP BY r.id, r.user_id, r.book_id, r.rating, r.review, r.status, r.created_at, r.updated_at, u.username, b.title, b.author, b.cover_url`
-	query += ` ORDER BY r.created_at DESC LIMIT $` + fmt.Sprintf("%d", argCount) + ` OFFSET $` + fmt.Sprintf("%d", argCount+1)
-	args = append(args, limit, offset)
+	// Reading entries and progress updates are merged into one timeline. kind is
+	// 0 for entries and 1 for progress updates, and breaks ties between the two
+	// id sequences when paging.
+	query := `
+    SELECT * FROM (
+        SELECT
+            0 as kind, r.id, r.user_id, r.book_id, r.rating, r.review, r.review_html, r.review_is_spoiler, r.edited_at, r.status, r.created_at, r.updated_at,
+            u.username,
+            b.title, b.author, b.cover_url, b.page_count,
+            COUNT(DISTINCT rl.user_id) as like_count,
+            COUNT(DISTINCT c.id) as comment_count,
+            ` + readCountExpr + ` as read_count,
+            ` + likedExpr + ` as liked_by_user,
+            ` + revealedExpr + ` as spoilers_revealed,
+            NULL::int as session_id, NULL::int as page, NULL::numeric as percent, NULL::text as comment
+        FROM ratings r
+        JOIN users u ON r.user_id = u.id
+        JOIN books b ON r.book_id = b.id
+        LEFT JOIN review_likes rl ON r.id = rl.rating_id
+        LEFT JOIN comments c ON r.id = c.rating_id
+        WHERE 1=1` + entryFilter + `
+        GROUP BY r.id, r.user_id, r.book_id, r.rating, r.review, r.review_html, r.review_is_spoiler, r.edited_at, r.status, r.created_at, r.updated_at, u.username, b.title, b.author, b.cover_url, b.page_count
+        UNION ALL
+        SELECT
+            1, p.id, r.user_id, r.book_id, 0, NULL, NULL, false, NULL, r.status, p.created_at, p.created_at,
+            u.username,
+            b.title, b.author, b.cover_url, b.page_count,
+            0, 0, 0, false, false,
+            p.session_id, p.page, p.percent, p.comment
+        FROM reading_progress p
+        JOIN reading_sessions s ON s.id = p.session_id
+        JOIN ratings r ON r.id = s.rating_id
+        JOIN users u ON r.user_id = u.id
+        JOIN books b ON r.book_id = b.id
+        WHERE 1=1` + entryFilter + `
+    ) feed`
+
+	if page.After != nil {
+		// The cursor's Value carries the kind of the last item
+		query += fmt.Sprintf(` WHERE (feed.created_at, feed.kind, feed.id) < ($%d, $%d, $%d)`, argCount, argCount+1, argCount+2)
+		args = append(args, page.After.Time, int(page.After.Value), page.After.ID)
+		argCount += 3
+	}
+
+	query += ` ORDER BY feed.created_at DESC, feed.kind DESC, feed.id DESC`
+	limitClause, limitArgs := pageLimit(page, argCount)
+	query += limitClause
+	args = append(args, limitArgs...)
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
 	items := []models.FeedItem{}
+	lastKind := 0
 	for rows.Next() {
 		var item models.FeedItem
-		var reviewNull sql.NullString
-		var coverNull sql.NullString
+		var reviewNull, reviewHTMLNull, coverNull, commentNull sql.NullString
+		var pageCount, sessionID, progressPage sql.NullInt64
+		var progressPercent sql.NullFloat64
+		var editedAt sql.NullTime
 		var ratingValue int
 
-		if requestingUserID != nil {
-			err := rows.Scan(
-				&item.ID, &item.UserID, &item.BookID, &ratingValue, &reviewNull, &item.Status,
-				&item.CreatedAt, &item.UpdatedAt, &item.Username,
-				&item.BookTitle, &item.BookAuthor, &coverNull,
-				&item.LikeCount, &item.CommentCount, &item.LikedByUser,
-			)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			err := rows.Scan(
-				&item.ID, &item.UserID, &item.BookID, &ratingValue, &reviewNull, &item.Status,
-				&item.CreatedAt, &item.UpdatedAt, &item.Username,
-				&item.BookTitle, &item.BookAuthor, &coverNull,
-				&item.LikeCount, &item.CommentCount,
-			)
-			if err != nil {
-				return nil, err
-			}
-			item.LikedByUser = false
+		err := rows.Scan(
+			&lastKind, &item.ID, &item.UserID, &item.BookID, &ratingValue, &reviewNull, &reviewHTMLNull, &item.Spoiler, &editedAt, &item.Status,
+			&item.CreatedAt, &item.UpdatedAt, &item.Username,
+			&item.BookTitle, &item.BookAuthor, &coverNull, &pageCount,
+			&item.LikeCount, &item.CommentCount, &item.ReadCount, &item.LikedByUser, &item.SpoilersRevealed,
+			&sessionID, &progressPage, &progressPercent, &commentNull,
+		)
+		if err != nil {
+			return nil, "", err
 		}
 
 		item.Rating.Rating = ratingValue
 		item.Review = reviewNull.String
+		item.ReviewHTML = renderedHTML(reviewHTMLNull, item.Review)
+		item.ReviewSegments = models.ParseReview(item.Review, item.Spoiler)
+		if editedAt.Valid {
+			item.EditedAt = &editedAt.Time
+		}
 		item.BookCover = coverNull.String
+		item.Type = "rating"
+		if lastKind == 1 {
+			item.Type = "progress"
+			item.Progress = &models.ReadingProgress{
+				ID:          item.ID,
+				SessionID:   int(sessionID.Int64),
+				BookID:      item.BookID,
+				PageCount:   int(pageCount.Int64),
+				Comment:     commentNull.String,
+				CommentHTML: markdown.Render(commentNull.String),
+				CreatedAt:   item.CreatedAt,
+			}
+			if progressPage.Valid {
+				pg := int(progressPage.Int64)
+				item.Progress.Page = &pg
+			}
+			if progressPercent.Valid {
+				item.Progress.Percent = &progressPercent.Float64
+			}
+		}
 		items = append(items, item)
 	}
-	return items, nil
+	next := ""
+	if len(items) > 0 {
+		last := items[len(items)-1]
+		next = nextCursor(page, len(items), models.Cursor{Value: float64(lastKind), Time: last.CreatedAt, ID: last.ID})
+	}
+	return items, next, nil
 }
 
 // Keep old GetFeed for backward compatibility if needed elsewhere
 func (r *RatingRepository) GetFeed(requestingUserID *int, limit, offset int) ([]models.FeedItem, error) {
-	return r.GetFeedByType(requestingUserID, "all", limit, offset)
+	items, _, err := r.GetFeedByType(requestingUserID, "all", models.PageParams{Limit: limit, Offset: offset})
+	return items, err
 }
 
-func (r *RatingRepository) GetByUserIDWithStatus(userID int, status string) ([]models.Rating, error) {
-	query := `SELECT id, user_id, book_id, rating, review, status, created_at, updated_at
-FROM ratings
-WHERE user_id = $1`
+func (r *RatingRepository) GetByUserIDWithStatus(userID int, status string, page models.PageParams) ([]models.Rating, string, error) {
+	query := ratingEntrySelect + `
+WHERE r.user_id = $1`
 
 	args := []interface{}{userID}
+	argCount := 2
 	if status != "" {
-		query += ` AND status = $2`
+		query += ` AND r.status = $2`
 		args = append(args, status)
+		argCount++
 	}
-	query += ` ORDER BY created_at DESC`
+	if page.After != nil {
+		cond, keysetArgs := keysetCondition(page.After, []string{"r.created_at", "r.id"}, true, argCount)
+		query += ` AND ` + cond
+		args = append(args, keysetArgs...)
+		argCount += len(keysetArgs)
+	}
+	query += ` ORDER BY r.created_at DESC, r.id DESC`
+	limitClause, limitArgs := pageLimit(page, argCount)
+	query += limitClause
+	args = append(args, limitArgs...)
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
 	ratings := []models.Rating{}
 	for rows.Next() {
-		var rating models.Rating
-		var reviewNull sql.NullString
-
-		err := rows.Scan(&rating.ID, &rating.UserID, &rating.BookID, &rating.Rating, &reviewNull, &rating.Status, &rating.CreatedAt, &rating.UpdatedAt)
+		rating, err := scanRatingEntry(rows)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
-		rating.Review = reviewNull.String
-		ratings = append(ratings, rating)
+		ratings = append(ratings, *rating)
 	}
-	return ratings, nil
+	next := ""
+	if len(ratings) > 0 {
+		last := ratings[len(ratings)-1]
+		next = nextCursor(page, len(ratings), models.Cursor{Time: last.CreatedAt, ID: last.ID})
+	}
+	return ratings, next, nil
 }
 
 func (r *RatingRepository) LikeRating(userID, ratingID int) error {
@@ -378,29 +709,132 @@ func (r *RatingRepository) HasUserLiked(userID, ratingID int) (bool, error) {
 	return exists, err
 }
 
-func (r *RatingRepository) Update(ratingID, userID, rating int, review string) (*models.Rating, error) {
-	query := `UPDATE ratings
-SET rating = $1, review = $2, updated_at = CURRENT_TIMESTAMP
-WHERE id = $3 AND user_id = $4
-RETURNING id, user_id, book_id, rating, review, status, created_at, updated_at`
+// GetRevisions returns a page of a review's earlier versions, most recently
+// replaced first. It returns sql.ErrNoRows if the rating doesn't exist or the
+// viewer can't see it.
+func (r *RatingRepository) GetRevisions(ratingID int, viewerID *int, page models.PageParams) ([]models.ReviewRevision, string, error) {
+	var exists bool
+	var err error
+	if viewerID != nil {
+		err = r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM ratings r WHERE r.id = $1 AND `+visibleTo("$2")+`)`, ratingID, *viewerID).Scan(&exists)
+	} else {
+		err = r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM ratings r WHERE r.id = $1 AND `+visibleTo("")+`)`, ratingID).Scan(&exists)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if !exists {
+		return nil, "", sql.ErrNoRows
+	}
 
-	ratingModel := &models.Rating{}
-	var reviewNull sql.NullString
+	query := `SELECT id, rating_id, review, review_html, review_is_spoiler, written_at, created_at
+FROM review_revisions
+WHERE rating_id = $1`
+	args := []interface{}{ratingID}
+	argCount := 2
+	if page.After != nil {
+		cond, keysetArgs := keysetCondition(page.After, []string{"created_at", "id"}, true, argCount)
+		query += ` AND ` + cond
+		args = append(args, keysetArgs...)
+		argCount += len(keysetArgs)
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+	limitClause, limitArgs := pageLimit(page, argCount)
+	query += limitClause
+	args = append(args, limitArgs...)
 
-	err := r.db.QueryRow(query, rating, nullString(review), ratingID, userID).Scan(
-		&ratingModel.ID, &ratingModel.UserID, &ratingModel.BookID, &ratingModel.Rating, &reviewNull, &ratingModel.Status, &ratingModel.CreatedAt, &ratingModel.UpdatedAt)
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	revisions := []models.ReviewRevision{}
+	for rows.Next() {
+		var rev models.ReviewRevision
+		var reviewNull, reviewHTMLNull sql.NullString
+		err := rows.Scan(&rev.ID, &rev.RatingID, &reviewNull, &reviewHTMLNull, &rev.Spoiler, &rev.WrittenAt, &rev.CreatedAt)
+		if err != nil {
+			return nil, "", err
+		}
+		rev.Review = reviewNull.String
+		rev.ReviewHTML = renderedHTML(reviewHTMLNull, rev.Review)
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(revisions) > 0 {
+		last := revisions[len(revisions)-1]
+		next = nextCursor(page, len(revisions), models.Cursor{Time: last.CreatedAt, ID: last.ID})
+	}
+	return revisions, next, nil
+}
+
+// Update changes the rating and review of the user's entry, keeping a replaced
+// review as a revision. The rating and any
+// given dates are also recorded on the read behind the entry's status so the
+// two stay in step: the latest finished read, or for a did-not-finish the
+// latest read that was given up on. With neither, a start date goes on the
+// read in progress.
+func (r *RatingRepository) Update(ratingID, userID, rating int, review string, spoiler bool, language string, privacy models.EntryPrivacy, dates models.ReadDates) (*models.Rating, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
 		return nil, err
 	}
-	if err == sql.ErrNoRows {
+	defer tx.Rollback()
+
+	// A rating that isn't the user's is left alone by the update below, which
+	// rolls this back
+	if err := saveReviewRevision(tx, ratingID, review); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE ratings
+SET rating = $1, review = $2, review_html = $3, review_is_spoiler = $4, review_language = $10,
+	visibility = COALESCE($7::entry_visibility, visibility),
+	private_notes = CASE WHEN $9::boolean THEN $8::text ELSE private_notes END,
+	updated_at = CURRENT_TIMESTAMP
+WHERE id = $5 AND user_id = $6`
+	result, err := tx.Exec(query, rating, nullString(review), nullString(markdown.Render(review)), spoiler, ratingID, userID,
+		nullString(privacy.Visibility), privateNotesArg(privacy), privacy.PrivateNotes != nil, reviewLanguageArg(review, language))
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
 		return nil, sql.ErrNoRows
 	}
-	ratingModel.Review = reviewNull.String
-	return ratingModel, nil
+
+	_, err = tx.Exec(`
+		UPDATE reading_sessions
+		SET rating = CASE WHEN NOT finished AND stopped_at IS NULL THEN rating ELSE $2 END,
+			started_at = `+sessionStartedAt+`,
+			finished_at = CASE WHEN NOT finished THEN NULL ELSE COALESCE($4::date, finished_at) END,
+			stopped_at = CASE WHEN stopped_at IS NULL THEN NULL ELSE COALESCE($5::date, stopped_at) END,
+			stopped_at_page = CASE WHEN stopped_at IS NULL THEN NULL ELSE COALESCE($6::int, stopped_at_page) END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = (SELECT s.id FROM reading_sessions s
+			JOIN ratings r ON r.id = s.rating_id
+			WHERE s.rating_id = $1
+			ORDER BY CASE WHEN r.status = 'did_not_finish' THEN s.stopped_at IS NULL ELSE NOT s.finished END,
+				COALESCE(s.finished_at, s.stopped_at) DESC NULLS LAST, s.id DESC
+			LIMIT 1)`, ratingID, rating, dates.StartedAt, dates.FinishedAt, dates.StoppedAt, dates.StoppedAtPage)
+	if err != nil {
+		return nil, err
+	}
+
+	ratingModel, err := scanRatingEntry(tx.QueryRow(ratingEntrySelect+` WHERE r.id = $1`, ratingID))
+	if err != nil {
+		return nil, err
+	}
+	return ratingModel, tx.Commit()
 }
 
 func (r *RatingRepository) GetTopRatedByUser(userID, limit int) ([]map[string]interface{}, error) {
-	query := `SELECT r.id as rating_id, r.rating, r.review, r.status, r.created_at, r.updated_at, b.id as book_id, b.title, b.author, b.cover_url FROM ratings r JOIN books b ON r.book_id = b.id WHERE r.user_id = $1 AND r.rating > 0 ORDER BY r.rating DESC, r.created_at DESC LIMIT $2`
+	query := `SELECT r.id as rating_id, r.rating, r.review, r.review_is_spoiler, r.status, r.created_at, r.updated_at, b.id as book_id, b.title, b.author, b.cover_url FROM ratings r JOIN books b ON r.book_id = b.id WHERE r.user_id = $1 AND r.rating > 0 AND ` + visibleTo("") + ` ORDER BY r.rating DESC, r.created_at DESC LIMIT $2`
 	rows, err := r.db.Query(query, userID, limit)
 	if err != nil {
 		return nil, err
@@ -412,41 +846,75 @@ func (r *RatingRepository) GetTopRatedByUser(userID, limit int) ([]map[string]in
 		var rating int
 		var title, author string
 		var review sql.NullString
+		var spoiler bool
 		var status, createdAt, updatedAt, coverURL sql.NullString
-		err := rows.Scan(&ratingID, &rating, &review, &status, &createdAt, &updatedAt, &bookID, &title, &author, &coverURL)
+		err := rows.Scan(&ratingID, &rating, &review, &spoiler, &status, &createdAt, &updatedAt, &bookID, &title, &author, &coverURL)
 		if err != nil {
 			return nil, err
 		}
-		reviewSnippet := ""
-		if review.Valid && len(review.String) > 150 {
-			reviewSnippet = review.String[:150] + "..."
-		} else if review.Valid {
-			reviewSnippet = review.String
+		// Embeds are public, so the snippet never shows spoilers. The HTML is
+		// rendered from the cut-down source; a spoiler cut off before its
+		// closing tag stays hidden.
+		segments := models.TruncateSegments(models.ParseReview(review.String, spoiler), 150)
+		reviewHTML := ""
+		if !spoiler {
+			source := []rune(review.String)
+			if len(source) > 150 {
+				source = append(source[:150], []rune("...")...)
+			}
+			reviewHTML = markdown.RenderSnippet(string(source))
 		}
 		books = append(books, map[string]interface{}{
-			"rating_id":      ratingID,
-			"book_id":        bookID,
-			"title":          title,
-			"author":         author,
-			"cover_url":      coverURL.String,
-			"rating":         rating,
-			"review_snippet": reviewSnippet,
-			"created_at":     createdAt.String,
-			"updated_at":     updatedAt.String,
+			"rating_id":       ratingID,
+			"book_id":         bookID,
+			"title":           title,
+			"author":          author,
+			"cover_url":       coverURL.String,
+			"rating":          rating,
+			"review_snippet":  models.SpoilerFreeText(segments, "[spoiler]"),
+			"review_segments": segments,
+			"review_html":     reviewHTML,
+			"spoiler":         spoiler,
+			"created_at":      createdAt.String,
+			"updated_at":      updatedAt.String,
 		})
 	}
 	return books, nil
 }
 
-func (r *RatingRepository) GetYearStats(userID, year int) (*models.UserYearStats, error) {
+// yearReads selects user $1's finished reads in year $2 that the viewer ($3,
+// when logged in) may see. Each read counts, so a book re-read within the year
+// is counted twice. Reads finished on an unknown date belong to no year, so
+// they're left out of the counts, monthly activity and streaks.
+func yearReads(viewerID *int) string {
+	viewer := ""
+	if viewerID != nil {
+		viewer = "$3"
+	}
+	return `WITH reads AS (
+	SELECT r.book_id, s.rating, s.finished_at
+	FROM reading_sessions s
+	JOIN ratings r ON r.id = s.rating_id
+	WHERE r.user_id = $1 AND s.finished_at IS NOT NULL AND EXTRACT(YEAR FROM s.finished_at) = $2
+		AND ` + visibleTo(viewer) + `
+)
+`
+}
+
+func (r *RatingRepository) GetYearStats(userID, year int, viewerID *int) (*models.UserYearStats, error) {
 	stats := &models.UserYearStats{Year: year}
-	countQuery := `SELECT COUNT(DISTINCT book_id) AS books_read, COALESCE(AVG(rating), 0) AS avg_rating FROM ratings WHERE user_id = $1 AND EXTRACT (YEAR FROM created_at) = $2 AND rating > 0`
-	err := r.db.QueryRow(countQuery, userID, year).Scan(&stats.BooksRead, &stats.AverageRating)
+	reads := yearReads(viewerID)
+	args := []interface{}{userID, year}
+	if viewerID != nil {
+		args = append(args, *viewerID)
+	}
+	countQuery := reads + `SELECT COUNT(*) AS books_read, COALESCE(AVG(rating) FILTER (WHERE rating > 0), 0) AS avg_rating FROM reads`
+	err := r.db.QueryRow(countQuery, args...).Scan(&stats.BooksRead, &stats.AverageRating)
 	if err != nil {
 		return nil, err
 	}
-	genresQuery := `SELECT g.name, COUNT(DISTINCT r.book_id) AS count FROM ratings r JOIN book_genres bg ON r.book_id = bg.book_id JOIN genres g ON bg.genre_id = g.id WHERE r.user_id = $1 AND EXTRACT(YEAR FROM r.created_at) = $2 AND r.rating > 0 GROUP BY g.name ORDER BY count DESC LIMIT 5`
-	rows, err := r.db.Query(genresQuery, userID, year)
+	genresQuery := reads + `SELECT g.name, COUNT(*) AS count FROM reads JOIN book_genres bg ON reads.book_id = bg.book_id JOIN genres g ON bg.genre_id = g.id GROUP BY g.name ORDER BY count DESC LIMIT 5`
+	rows, err := r.db.Query(genresQuery, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -459,8 +927,8 @@ func (r *RatingRepository) GetYearStats(userID, year int) (*models.UserYearStats
 		}
 		stats.TopGenres = append(stats.TopGenres, gc)
 	}
-	authorsQuery := `SELECT b.author, COUNT(DISTINCT r.book_id) AS count FROM ratings r JOIN books b ON r.book_id = b.id WHERE r.user_id = $1 AND EXTRACT(YEAR FROM r.created_at) = $2 AND r.rating > 0 GROUP BY b.author ORDER BY count DESC LIMIT 5`
-	rows, err = r.db.Query(authorsQuery, userID, year)
+	authorsQuery := reads + `SELECT a.id, a.name, COUNT(*) AS count FROM reads JOIN book_authors ba ON reads.book_id = ba.book_id AND ba.role = 'author' JOIN authors a ON ba.author_id = a.id GROUP BY a.id, a.name ORDER BY count DESC LIMIT 5`
+	rows, err = r.db.Query(authorsQuery, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -468,13 +936,13 @@ func (r *RatingRepository) GetYearStats(userID, year int) (*models.UserYearStats
 	stats.FavouriteAuthors = []models.AuthorCount{}
 	for rows.Next() {
 		var ac models.AuthorCount
-		if err := rows.Scan(&ac.Author, &ac.Count); err != nil {
+		if err := rows.Scan(&ac.AuthorID, &ac.Author, &ac.Count); err != nil {
 			return nil, err
 		}
 		stats.FavouriteAuthors = append(stats.FavouriteAuthors, ac)
 	}
-	monthlyQuery := `SELECT EXTRACT(MONTH FROM created_at)::int AS month, COUNT (DISTINCT book_id) AS count FROM ratings WHERE user_id = $1 AND EXTRACT(YEAR FROM created_at) = $2 AND rating > 0 GROUP BY month ORDER BY month`
-	rows, err = r.db.Query(monthlyQuery, userID, year)
+	monthlyQuery := reads + `SELECT EXTRACT(MONTH FROM finished_at)::int AS month, COUNT(*) AS count FROM reads GROUP BY month ORDER BY month`
+	rows, err = r.db.Query(monthlyQuery, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -487,13 +955,10 @@ func (r *RatingRepository) GetYearStats(userID, year int) (*models.UserYearStats
 		}
 		stats.MonthlyActivity = append(stats.MonthlyActivity, mc)
 	}
-	streakQuery := `
-			WITH daily_reads AS (
-				SELECT DISTINCT DATE(created_at) as read_date
-				FROM ratings
-				WHERE user_id = $1
-					AND EXTRACT(YEAR FROM created_at) = $2
-					AND rating > 0
+	streakQuery := reads + `,
+			daily_reads AS (
+				SELECT DISTINCT finished_at as read_date
+				FROM reads
 				ORDER BY read_date
 			),
 			streaks AS (
@@ -509,9 +974,68 @@ func (r *RatingRepository) GetYearStats(userID, year int) (*models.UserYearStats
 				GROUP BY streak_group
 			) sub
 		`
-	err = r.db.QueryRow(streakQuery, userID, year).Scan(&stats.ReadingStreak)
+	err = r.db.QueryRow(streakQuery, args...).Scan(&stats.ReadingStreak)
 	if err != nil {
 		return nil, err
 	}
 	return stats, nil
 }
+
+// GetAuthorStats aggregates ratings across every book the author is credited on.
+// Readers are users who are reading or have finished at least one of those books.
+func (r *RatingRepository) GetAuthorStats(authorID int) (avgRating float64, ratingCount, readerCount int, err error) {
+	query := `SELECT
+	COALESCE(AVG(r.rating) FILTER (WHERE r.rating > 0), 0),
+	COUNT(r.id) FILTER (WHERE r.rating > 0),
+	COUNT(DISTINCT r.user_id) FILTER (WHERE r.status IN ('currently_reading', 'finished_reading', 'did_not_finish'))
+FROM ratings r
+WHERE r.book_id IN (SELECT book_id FROM book_authors WHERE author_id = $1)`
+	err = r.db.QueryRow(query, authorID).Scan(&avgRating, &ratingCount, &readerCount)
+	return avgRating, ratingCount, readerCount, err
+}
+
+// GetStatusesForBooks returns the user's reading status for each of the given books they have shelved.
+func (r *RatingRepository) GetStatusesForBooks(userID int, bookIDs []int) (map[int]string, error) {
+	statuses := map[int]string{}
+	if len(bookIDs) == 0 {
+		return statuses, nil
+	}
+	query := `SELECT book_id, status FROM ratings WHERE user_id = $1 AND book_id = ANY($2)`
+	rows, err := r.db.Query(query, userID, pq.Array(bookIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bookID int
+		var status string
+		if err := rows.Scan(&bookID, &status); err != nil {
+			return nil, err
+		}
+		statuses[bookID] = status
+	}
+	return statuses, nil
+}
+
+// GetStatusesForAuthor groups the user's shelved books by this author by reading status.
+func (r *RatingRepository) GetStatusesForAuthor(userID, authorID int) (map[string][]int, error) {
+	query := `SELECT r.status, r.book_id
+FROM ratings r
+WHERE r.user_id = $1 AND r.book_id IN (SELECT book_id FROM book_authors WHERE author_id = $2)
+ORDER BY r.updated_at DESC`
+	rows, err := r.db.Query(query, userID, authorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	shelves := map[string][]int{}
+	for rows.Next() {
+		var status string
+		var bookID int
+		if err := rows.Scan(&status, &bookID); err != nil {
+			return nil, err
+		}
+		shelves[status] = append(shelves[status], bookID)
+	}
+	return shelves, nil
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+// suggestSimilarityThreshold is lower than pg_trgm's default (0.6) so common misspellings
+// like "tolkein" still match "Tolkien".
+const suggestSimilarityThreshold = 0.3
+
+type SearchRepository struct {
+	db *sql.DB
+}
+
+func NewSearchRepository(db *sql.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// Suggest returns a mixed, ranked set of books, authors, users and public lists for typeahead.
+// Each source is capped at limit before merging so one noisy source can't crowd out the others.
+func (r *SearchRepository) Suggest(ctx context.Context, q string, limit int) ([]models.Suggestion, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`,
+		strconv.FormatFloat(suggestSimilarityThreshold, 'f', -1, 64)); err != nil {
+		return nil, err
+	}
+
+	// Prefix matches get a flat boost so "the ho" ranks "The Hobbit" above fuzzy hits.
+	query := `
+SELECT type, id, label, detail, cover_url, score FROM (
+	(SELECT 'book' AS type, b.id, b.title AS label, b.author AS detail, COALESCE(b.cover_url, '') AS cover_url,
+		word_similarity($1, b.title) + CASE WHEN b.title ILIKE $3 THEN 0.5 ELSE 0 END AS score
+	FROM books b
+	WHERE $1 <% b.title OR b.title ILIKE $3
+	ORDER BY score DESC
+	LIMIT $2)
+	UNION ALL
+	(SELECT 'author', a.id, a.name, COUNT(ba.book_id)::text || ' books', '',
+		word_similarity($1, a.name) + CASE WHEN a.name ILIKE $3 THEN 0.5 ELSE 0 END AS score
+	FROM authors a
+	JOIN book_authors ba ON a.id = ba.author_id
+	WHERE $1 <% a.name OR a.name ILIKE $3
+	GROUP BY a.id, a.name
+	ORDER BY score DESC
+	LIMIT $2)
+	UNION ALL
+	(SELECT 'user', u.id, u.username, '', '',
+		word_similarity($1, u.username) + CASE WHEN u.username ILIKE $3 THEN 0.5 ELSE 0 END AS score
+	FROM users u
+	WHERE $1 <% u.username OR u.username ILIKE $3
+	ORDER BY score DESC
+	LIMIT $2)
+	UNION ALL
+	(SELECT 'list', l.id, l.name, u.username, '',
+		word_similarity($1, l.name) + CASE WHEN l.name ILIKE $3 THEN 0.5 ELSE 0 END AS score
+	FROM lists l
+	JOIN users u ON l.user_id = u.id
+	WHERE l.public = true AND ($1 <% l.name OR l.name ILIKE $3)
+	ORDER BY score DESC
+	LIMIT $2)
+) s
+ORDER BY score DESC
+LIMIT $2`
+
+	rows, err := tx.QueryContext(ctx, query, q, limit, escapeLike(q)+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []models.Suggestion{}
+	for rows.Next() {
+		var s models.Suggestion
+		if err := rows.Scan(&s.Type, &s.ID, &s.Label, &s.Detail, &s.CoverURL, &s.Score); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, rows.Err()
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes user input safe to use as a literal inside a LIKE/ILIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
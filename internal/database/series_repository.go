@@ -0,0 +1,127 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+type SeriesRepository struct {
+	db *sql.DB
+}
+
+func NewSeriesRepository(db *sql.DB) *SeriesRepository {
+	return &SeriesRepository{db: db}
+}
+
+func (r *SeriesRepository) Create(name, description string) (*models.Series, error) {
+	query := `INSERT INTO series (name, description) VALUES ($1, $2) RETURNING id, name, description, created_at, updated_at`
+	series := &models.Series{}
+	var descNull sql.NullString
+	err := r.db.QueryRow(query, name, nullString(description)).Scan(&series.ID, &series.Name, &descNull, &series.CreatedAt, &series.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	series.Description = descNull.String
+	return series, nil
+}
+
+// GetByID returns the series in reading order. When viewerID is set, each entry carries the
+// viewer's status and NextUnread points at the first entry they haven't finished.
+func (r *SeriesRepository) GetByID(seriesID int, viewerID *int) (*models.SeriesWithEntries, error) {
+	seriesQuery := `SELECT id, name, description, created_at, updated_at FROM series WHERE id = $1`
+	series := &models.SeriesWithEntries{}
+	var descNull sql.NullString
+	err := r.db.QueryRow(seriesQuery, seriesID).Scan(&series.ID, &series.Name, &descNull, &series.CreatedAt, &series.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, sql.ErrNoRows
+	}
+	if err != nil {
+		return nil, err
+	}
+	series.Description = descNull.String
+
+	var viewer interface{}
+	if viewerID != nil {
+		viewer = *viewerID
+	}
+	entriesQuery := `SELECT se.position, se.work_id, b.id, b.title, b.author, b.cover_url, b.published_year,
+	(SELECT r.status FROM ratings r JOIN books eb ON r.book_id = eb.id
+	 WHERE eb.work_id = se.work_id AND r.user_id = $2
+	 ORDER BY (r.status = 'finished_reading') DESC, r.updated_at DESC
+	 LIMIT 1) AS viewer_status
+FROM series_entries se
+` + primaryEditionSeriesJoin + `
+WHERE se.series_id = $1
+ORDER BY se.position ASC, b.published_year ASC NULLS LAST`
+	rows, err := r.db.Query(entriesQuery, seriesID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series.Entries = []models.SeriesEntry{}
+	for rows.Next() {
+		var entry models.SeriesEntry
+		var coverNull, statusNull sql.NullString
+		var yearNull sql.NullInt64
+		err := rows.Scan(&entry.Position, &entry.WorkID, &entry.BookID, &entry.Title, &entry.Author, &coverNull, &yearNull, &statusNull)
+		if err != nil {
+			return nil, err
+		}
+		entry.CoverURL = coverNull.String
+		entry.PublishedYear = int(yearNull.Int64)
+		entry.ViewerStatus = statusNull.String
+		series.Entries = append(series.Entries, entry)
+	}
+
+	if viewerID != nil {
+		for i := range series.Entries {
+			if series.Entries[i].ViewerStatus != "finished_reading" {
+				series.NextUnread = &series.Entries[i]
+				break
+			}
+		}
+	}
+	return series, nil
+}
+
+// primaryEditionSeriesJoin resolves each series entry to the oldest edition of its work.
+const primaryEditionSeriesJoin = `JOIN LATERAL (
+	SELECT id, title, author, cover_url, published_year FROM books WHERE work_id = se.work_id ORDER BY id LIMIT 1
+) b ON true`
+
+// AddBook places the book's work in the series, moving it if it is already there.
+func (r *SeriesRepository) AddBook(seriesID, bookID int, position float64) error {
+	query := `INSERT INTO series_entries (series_id, work_id, position)
+SELECT $1, work_id, $3 FROM books WHERE id = $2
+ON CONFLICT (series_id, work_id) DO UPDATE SET position = EXCLUDED.position`
+	result, err := r.db.Exec(query, seriesID, bookID, position)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SeriesRepository) RemoveBook(seriesID, bookID int) error {
+	query := `DELETE FROM series_entries WHERE series_id = $1 AND work_id = (SELECT work_id FROM books WHERE id = $2)`
+	result, err := r.db.Exec(query, seriesID, bookID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
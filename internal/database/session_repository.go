@@ -0,0 +1,364 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+// readCountExpr counts the finished reads of the rating aliased r.
+const readCountExpr = `(SELECT COUNT(*) FROM reading_sessions s WHERE s.rating_id = r.id AND s.finished)`
+
+// latestReadRatingExpr is the rating given on the most recent finished read of the rating aliased r.
+const latestReadRatingExpr = `(SELECT s.rating FROM reading_sessions s
+	WHERE s.rating_id = r.id AND s.finished
+	ORDER BY s.finished_at DESC NULLS LAST, s.id DESC LIMIT 1)`
+
+type ReadingSessionRepository struct {
+	db *sql.DB
+}
+
+func NewReadingSessionRepository(db *sql.DB) *ReadingSessionRepository {
+	return &ReadingSessionRepository{db: db}
+}
+
+const sessionSelect = `SELECT s.id, s.rating_id, r.book_id, s.started_at, s.finished, s.finished_at, s.stopped_at, s.stopped_at_page, s.rating, s.notes, s.created_at, s.updated_at
+FROM reading_sessions s
+JOIN ratings r ON r.id = s.rating_id`
+
+func scanSession(row rowScanner) (*models.ReadingSession, error) {
+	s := &models.ReadingSession{}
+	var startedAt, finishedAt, stoppedAt sql.NullTime
+	var stoppedAtPage sql.NullInt64
+	var notes sql.NullString
+	err := row.Scan(&s.ID, &s.RatingID, &s.BookID, &startedAt, &s.Finished, &finishedAt, &stoppedAt, &stoppedAtPage, &s.Rating, &notes, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if startedAt.Valid {
+		s.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		s.FinishedAt = &finishedAt.Time
+	}
+	if stoppedAt.Valid {
+		s.StoppedAt = &stoppedAt.Time
+	}
+	if stoppedAtPage.Valid {
+		page := int(stoppedAtPage.Int64)
+		s.StoppedAtPage = &page
+	}
+	s.Notes = notes.String
+	return s, nil
+}
+
+// ListForBook returns the user's reads of a book, the one in progress first and then newest first.
+func (r *ReadingSessionRepository) ListForBook(userID, bookID int) ([]models.ReadingSession, error) {
+	query := sessionSelect + `
+WHERE r.user_id = $1 AND r.book_id = $2
+ORDER BY NOT s.finished AND s.stopped_at IS NULL DESC, COALESCE(s.finished_at, s.stopped_at) DESC NULLS LAST, s.id DESC`
+	rows, err := r.db.Query(query, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.ReadingSession{}
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *s)
+	}
+	return sessions, rows.Err()
+}
+
+// Create logs a read, shelving the book first if needed. A read without a
+// finish or stop date becomes the read in progress.
+func (r *ReadingSessionRepository) Create(userID, bookID int, dates models.ReadDates, rating int, notes string) (*models.ReadingSession, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var ratingID int
+	err = tx.QueryRow(`
+		INSERT INTO ratings (user_id, book_id, rating, status)
+		VALUES ($1, $2, 0, 'to_read')
+		ON CONFLICT (user_id, book_id) DO UPDATE SET updated_at = ratings.updated_at
+		RETURNING id`, userID, bookID).Scan(&ratingID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		return nil, fmt.Errorf("book not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sessionID int
+	err = tx.QueryRow(`
+		INSERT INTO reading_sessions (rating_id, started_at, finished, finished_at, stopped_at, stopped_at_page, rating, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`, ratingID, dates.StartedAt, dates.Finished(), dates.FinishedAt, dates.StoppedAt, dates.StoppedAtPage, rating, nullString(notes)).Scan(&sessionID)
+	if isUniqueViolation(err) {
+		return nil, models.ErrReadInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := refreshEntry(tx, ratingID, true); err != nil {
+		return nil, err
+	}
+
+	session, err := scanSession(tx.QueryRow(sessionSelect+` WHERE s.id = $1`, sessionID))
+	if err != nil {
+		return nil, err
+	}
+	return session, tx.Commit()
+}
+
+// Update edits one of the user's reads. Changing how the read ended, or
+// reopening it, moves the book between currently reading, finished and did
+// not finish.
+func (r *ReadingSessionRepository) Update(sessionID, userID int, dates models.ReadDates, rating int, notes string) (*models.ReadingSession, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var ratingID int
+	var wasFinished, wasStopped bool
+	err = tx.QueryRow(`
+		SELECT s.rating_id, s.finished, s.stopped_at IS NOT NULL
+		FROM reading_sessions s
+		JOIN ratings r ON r.id = s.rating_id
+		WHERE s.id = $1 AND r.user_id = $2
+		FOR UPDATE OF s`, sessionID, userID).Scan(&ratingID, &wasFinished, &wasStopped)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE reading_sessions
+		SET started_at = $1, finished = $2, finished_at = $3, stopped_at = $4, stopped_at_page = $5, rating = $6, notes = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8`, dates.StartedAt, dates.Finished(), dates.FinishedAt, dates.StoppedAt, dates.StoppedAtPage, rating, nullString(notes), sessionID)
+	if isUniqueViolation(err) {
+		return nil, models.ErrReadInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	outcomeChanged := wasFinished != dates.Finished() || wasStopped != (dates.StoppedAt != nil)
+	if err := refreshEntry(tx, ratingID, outcomeChanged); err != nil {
+		return nil, err
+	}
+
+	session, err := scanSession(tx.QueryRow(sessionSelect+` WHERE s.id = $1`, sessionID))
+	if err != nil {
+		return nil, err
+	}
+	return session, tx.Commit()
+}
+
+// Delete removes one of the user's reads and returns the book it belonged to.
+func (r *ReadingSessionRepository) Delete(sessionID, userID int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var ratingID, bookID int
+	err = tx.QueryRow(`
+		DELETE FROM reading_sessions s
+		USING ratings r
+		WHERE s.id = $1 AND r.id = s.rating_id AND r.user_id = $2
+		RETURNING r.id, r.book_id`, sessionID, userID).Scan(&ratingID, &bookID)
+	if err != nil {
+		return 0, err
+	}
+	if err := refreshEntry(tx, ratingID, true); err != nil {
+		return 0, err
+	}
+	return bookID, tx.Commit()
+}
+
+// sessionStartedAt is the start date to store when a session's dates change:
+// the start date in $3 if given, otherwise the current one unless it would now
+// fall after the finish date in $4 or the stop date in $5.
+const sessionStartedAt = `CASE
+	WHEN $3::date IS NOT NULL THEN $3::date
+	WHEN started_at > COALESCE($4::date, $5::date, finished_at, stopped_at) THEN NULL
+	ELSE started_at
+END`
+
+// sessionFinishedAt is the finish date to store when a read is finished: the
+// date in $4 if given, none if $6 says the date isn't known, and otherwise
+// today, or the start date in $3 if that's later.
+const sessionFinishedAt = `CASE
+	WHEN $6::boolean THEN $4::date
+	ELSE COALESCE($4::date, GREATEST(CURRENT_DATE, COALESCE($3::date, started_at)))
+END`
+
+// lastProgressPage is the page of the latest progress update on the session
+// being updated, used as the stopped-at page when none is given.
+const lastProgressPage = `(SELECT p.page FROM reading_progress p
+	WHERE p.session_id = reading_sessions.id
+	ORDER BY p.created_at DESC, p.id DESC LIMIT 1)`
+
+// syncReadingSession keeps the sessions in step with a status set through the
+// rating endpoints: starting a book opens a read, finishing closes it (or logs
+// a new one), giving up stops it, and moving it back to to-read abandons the
+// read in progress. Dates that aren't given default to today, except a finish
+// date marked as unknown, which is left empty.
+func syncReadingSession(tx *sql.Tx, ratingID int, prevStatus, status string, rating int, dates models.ReadDates) error {
+	switch status {
+	case "currently_reading":
+		result, err := tx.Exec(`
+			UPDATE reading_sessions
+			SET started_at = COALESCE($2::date, started_at), updated_at = CURRENT_TIMESTAMP
+			WHERE rating_id = $1 AND NOT finished AND stopped_at IS NULL`, ratingID, dates.StartedAt)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n > 0 {
+			return err
+		}
+		_, err = tx.Exec(`
+			INSERT INTO reading_sessions (rating_id, started_at)
+			VALUES ($1, COALESCE($2::date, CURRENT_DATE))`, ratingID, dates.StartedAt)
+		return err
+	case "finished_reading":
+		result, err := tx.Exec(`
+			UPDATE reading_sessions
+			SET started_at = `+sessionStartedAt+`,
+				finished = TRUE,
+				finished_at = `+sessionFinishedAt+`,
+				rating = $2,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE rating_id = $1 AND NOT finished AND stopped_at IS NULL`, ratingID, rating, dates.StartedAt, dates.FinishedAt, dates.StoppedAt, dates.FinishedUndated)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n > 0 {
+			return err
+		}
+		latestRead := ""
+		latestArgs := []interface{}{ratingID, rating, dates.StartedAt, dates.FinishedAt, dates.StoppedAt}
+		switch prevStatus {
+		case "finished_reading":
+			// Already finished, so this re-rates the latest read rather than logging another
+			latestRead = `
+				UPDATE reading_sessions
+				SET started_at = ` + sessionStartedAt + `,
+					finished_at = COALESCE($4::date, finished_at),
+					rating = $2,
+					updated_at = CURRENT_TIMESTAMP
+				WHERE id = (SELECT id FROM reading_sessions
+					WHERE rating_id = $1 AND finished
+					ORDER BY finished_at DESC NULLS LAST, id DESC LIMIT 1)`
+		case "did_not_finish":
+			// Finishing a book that was given up on completes that read
+			latestRead = `
+				UPDATE reading_sessions
+				SET started_at = ` + sessionStartedAt + `,
+					finished = TRUE,
+					finished_at = ` + sessionFinishedAt + `,
+					stopped_at = NULL,
+					stopped_at_page = NULL,
+					rating = $2,
+					updated_at = CURRENT_TIMESTAMP
+				WHERE id = (SELECT id FROM reading_sessions
+					WHERE rating_id = $1 AND stopped_at IS NOT NULL
+					ORDER BY stopped_at DESC, id DESC LIMIT 1)`
+			latestArgs = append(latestArgs, dates.FinishedUndated)
+		}
+		if latestRead != "" {
+			result, err := tx.Exec(latestRead, latestArgs...)
+			if err != nil {
+				return err
+			}
+			if n, err := result.RowsAffected(); err != nil || n > 0 {
+				return err
+			}
+		}
+		_, err = tx.Exec(`
+			INSERT INTO reading_sessions (rating_id, started_at, finished, finished_at, rating)
+			VALUES ($1, $3::date, TRUE, CASE WHEN $5::boolean THEN NULL ELSE COALESCE($4::date, GREATEST(CURRENT_DATE, $3::date)) END, $2)`,
+			ratingID, rating, dates.StartedAt, dates.FinishedAt, dates.FinishedUndated)
+		return err
+	case "did_not_finish":
+		// Giving up stops the read in progress where the last progress update left off
+		result, err := tx.Exec(`
+			UPDATE reading_sessions
+			SET started_at = `+sessionStartedAt+`,
+				stopped_at = COALESCE($5::date, GREATEST(CURRENT_DATE, COALESCE($3::date, started_at))),
+				stopped_at_page = COALESCE($6::int, `+lastProgressPage+`),
+				rating = $2,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE rating_id = $1 AND NOT finished AND stopped_at IS NULL`,
+			ratingID, rating, dates.StartedAt, dates.FinishedAt, dates.StoppedAt, dates.StoppedAtPage)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n > 0 {
+			return err
+		}
+		if prevStatus == "did_not_finish" {
+			result, err := tx.Exec(`
+				UPDATE reading_sessions
+				SET started_at = `+sessionStartedAt+`,
+					stopped_at = COALESCE($5::date, stopped_at),
+					stopped_at_page = COALESCE($6::int, stopped_at_page),
+					rating = $2,
+					updated_at = CURRENT_TIMESTAMP
+				WHERE id = (SELECT id FROM reading_sessions
+					WHERE rating_id = $1 AND stopped_at IS NOT NULL
+					ORDER BY stopped_at DESC, id DESC LIMIT 1)`,
+				ratingID, rating, dates.StartedAt, dates.FinishedAt, dates.StoppedAt, dates.StoppedAtPage)
+			if err != nil {
+				return err
+			}
+			if n, err := result.RowsAffected(); err != nil || n > 0 {
+				return err
+			}
+		}
+		_, err = tx.Exec(`
+			INSERT INTO reading_sessions (rating_id, started_at, stopped_at, stopped_at_page, rating)
+			VALUES ($1, $3::date, COALESCE($4::date, GREATEST(CURRENT_DATE, $3::date)), $5, $2)`,
+			ratingID, rating, dates.StartedAt, dates.StoppedAt, dates.StoppedAtPage)
+		return err
+	default:
+		_, err := tx.Exec(`DELETE FROM reading_sessions WHERE rating_id = $1 AND NOT finished AND stopped_at IS NULL`, ratingID)
+		return err
+	}
+}
+
+// refreshEntry points a reading entry's rating at its latest finished read.
+// With syncStatus the status is also derived from the sessions: a read in
+// progress means currently reading, a latest read that was given up on means
+// did not finish, and otherwise any finished read means finished.
+func refreshEntry(tx *sql.Tx, ratingID int, syncStatus bool) error {
+	query := `UPDATE ratings r
+SET rating = COALESCE(` + latestReadRatingExpr + `, r.rating), updated_at = CURRENT_TIMESTAMP
+WHERE r.id = $1`
+	if syncStatus {
+		query = `UPDATE ratings r
+SET rating = COALESCE(` + latestReadRatingExpr + `, 0),
+	status = CASE
+		WHEN EXISTS (SELECT 1 FROM reading_sessions s WHERE s.rating_id = r.id AND NOT s.finished AND s.stopped_at IS NULL) THEN 'currently_reading'::reading_status
+		WHEN (SELECT s.stopped_at IS NOT NULL FROM reading_sessions s WHERE s.rating_id = r.id
+			ORDER BY COALESCE(s.finished_at, s.stopped_at) DESC NULLS LAST, s.id DESC LIMIT 1) THEN 'did_not_finish'::reading_status
+		WHEN EXISTS (SELECT 1 FROM reading_sessions s WHERE s.rating_id = r.id AND s.finished) THEN 'finished_reading'::reading_status
+		ELSE 'to_read'::reading_status
+	END,
+	updated_at = CURRENT_TIMESTAMP
+WHERE r.id = $1`
+	}
+	_, err := tx.Exec(query, ratingID)
+	return err
+}
@@ -0,0 +1,211 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+type ShelfRepository struct {
+	db *sql.DB
+}
+
+func NewShelfRepository(db *sql.DB) *ShelfRepository {
+	return &ShelfRepository{db: db}
+}
+
+// shelfBookVisible is true when the owner of shelf s has no entry for the book
+// on shelf_books sb that is hidden from the viewer in viewerParam, so a
+// private entry doesn't show up through the owner's shelves.
+func shelfBookVisible(viewerParam string) string {
+	return `NOT EXISTS (SELECT 1 FROM ratings r
+	WHERE r.user_id = s.user_id AND r.book_id = sb.book_id AND NOT ` + visibleTo(viewerParam) + `)`
+}
+
+// shelfSelect selects shelves with a count of the books the viewer in
+// viewerParam can see on them.
+func shelfSelect(viewerParam string) string {
+	return `SELECT s.id, s.user_id, s.name, s.description, s.created_at, s.updated_at,
+	(SELECT COUNT(*) FROM shelf_books sb WHERE sb.shelf_id = s.id AND ` + shelfBookVisible(viewerParam) + `)
+FROM shelves s`
+}
+
+// viewerArgs appends the viewer to args when there is one, returning the
+// parameter it's bound to.
+func viewerArgs(args []interface{}, viewerID *int) ([]interface{}, string) {
+	if viewerID == nil {
+		return args, ""
+	}
+	args = append(args, *viewerID)
+	return args, fmt.Sprintf("$%d", len(args))
+}
+
+func scanShelf(row rowScanner) (*models.Shelf, error) {
+	shelf := &models.Shelf{}
+	var descNull sql.NullString
+	err := row.Scan(&shelf.ID, &shelf.UserID, &shelf.Name, &descNull, &shelf.CreatedAt, &shelf.UpdatedAt, &shelf.BookCount)
+	if err != nil {
+		return nil, err
+	}
+	shelf.Description = descNull.String
+	return shelf, nil
+}
+
+func (r *ShelfRepository) Create(userID int, name, description string) (*models.Shelf, error) {
+	var shelfID int
+	err := r.db.QueryRow(`INSERT INTO shelves (user_id, name, description) VALUES ($1, $2, $3) RETURNING id`,
+		userID, name, nullString(description)).Scan(&shelfID)
+	if isUniqueViolation(err) {
+		return nil, models.ErrShelfExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(shelfID, &userID)
+}
+
+// GetByID returns a shelf with the number of books on it the viewer can see.
+func (r *ShelfRepository) GetByID(shelfID int, viewerID *int) (*models.Shelf, error) {
+	args, viewer := viewerArgs([]interface{}{shelfID}, viewerID)
+	return scanShelf(r.db.QueryRow(shelfSelect(viewer)+` WHERE s.id = $1`, args...))
+}
+
+// GetByUserID returns all of a user's shelves by name, with the number of
+// books on each the viewer can see.
+func (r *ShelfRepository) GetByUserID(userID int, viewerID *int) ([]models.Shelf, error) {
+	return userShelves(r.db, userID, viewerID)
+}
+
+func userShelves(db *sql.DB, userID int, viewerID *int) ([]models.Shelf, error) {
+	args, viewer := viewerArgs([]interface{}{userID}, viewerID)
+	rows, err := db.Query(shelfSelect(viewer)+` WHERE s.user_id = $1 ORDER BY LOWER(s.name)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shelves := []models.Shelf{}
+	for rows.Next() {
+		shelf, err := scanShelf(rows)
+		if err != nil {
+			return nil, err
+		}
+		shelves = append(shelves, *shelf)
+	}
+	return shelves, rows.Err()
+}
+
+func (r *ShelfRepository) Update(shelfID, userID int, name, description string) (*models.Shelf, error) {
+	result, err := r.db.Exec(`UPDATE shelves SET name = $1, description = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3 AND user_id = $4`,
+		name, nullString(description), shelfID, userID)
+	if isUniqueViolation(err) {
+		return nil, models.ErrShelfExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return r.GetByID(shelfID, &userID)
+}
+
+func (r *ShelfRepository) Delete(shelfID, userID int) error {
+	result, err := r.db.Exec(`DELETE FROM shelves WHERE id = $1 AND user_id = $2`, shelfID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetBooks returns a page of the books on a shelf, most recently added first.
+// Books whose entry the viewer can't see are left out.
+func (r *ShelfRepository) GetBooks(shelfID int, viewerID *int, page models.PageParams) ([]models.ShelfBook, string, error) {
+	args, viewer := viewerArgs([]interface{}{shelfID}, viewerID)
+	argCount := len(args) + 1
+	query := `SELECT sb.book_id, b.title, b.author, b.cover_url, r.status, sb.added_at
+FROM shelf_books sb
+JOIN shelves s ON s.id = sb.shelf_id
+JOIN books b ON b.id = sb.book_id
+LEFT JOIN ratings r ON r.user_id = s.user_id AND r.book_id = sb.book_id
+WHERE sb.shelf_id = $1 AND ` + shelfBookVisible(viewer)
+	if page.After != nil {
+		cond, keysetArgs := keysetCondition(page.After, []string{"sb.added_at", "sb.book_id"}, true, argCount)
+		query += ` AND ` + cond
+		args = append(args, keysetArgs...)
+		argCount += len(keysetArgs)
+	}
+	query += ` ORDER BY sb.added_at DESC, sb.book_id DESC`
+	limitClause, limitArgs := pageLimit(page, argCount)
+	query += limitClause
+	args = append(args, limitArgs...)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	books := []models.ShelfBook{}
+	for rows.Next() {
+		var book models.ShelfBook
+		var coverNull, statusNull sql.NullString
+		if err := rows.Scan(&book.BookID, &book.Title, &book.Author, &coverNull, &statusNull, &book.AddedAt); err != nil {
+			return nil, "", err
+		}
+		book.CoverURL = coverNull.String
+		book.Status = statusNull.String
+		books = append(books, book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(books) > 0 {
+		last := books[len(books)-1]
+		next = nextCursor(page, len(books), models.Cursor{Time: last.AddedAt, ID: last.BookID})
+	}
+	return books, next, nil
+}
+
+// AddBook puts a book on a shelf. Adding a book that's already there is a no-op.
+func (r *ShelfRepository) AddBook(shelfID, bookID int) error {
+	_, err := r.db.Exec(`INSERT INTO shelf_books (shelf_id, book_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, shelfID, bookID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		return fmt.Errorf("book not found")
+	}
+	return err
+}
+
+func (r *ShelfRepository) RemoveBook(shelfID, bookID int) error {
+	result, err := r.db.Exec(`DELETE FROM shelf_books WHERE shelf_id = $1 AND book_id = $2`, shelfID, bookID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// FindOrCreate returns the id of the user's shelf with this name, matched
+// case-insensitively, creating it if needed.
+func (r *ShelfRepository) FindOrCreate(userID int, name string) (int, error) {
+	var shelfID int
+	err := r.db.QueryRow(`
+		INSERT INTO shelves (user_id, name) VALUES ($1, $2)
+		ON CONFLICT (user_id, LOWER(name)) DO UPDATE SET name = shelves.name
+		RETURNING id`, userID, name).Scan(&shelfID)
+	return shelfID, err
+}
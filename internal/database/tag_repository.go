@@ -0,0 +1,214 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+// Abuse limits for community tagging.
+const (
+	maxTagsPerUserPerWork     = 10
+	maxNewTagsPerUserPerDay   = 20
+	maxTagVotesPerUserPerHour = 60
+)
+
+type TagRepository struct {
+	db *sql.DB
+}
+
+func NewTagRepository(db *sql.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+// GetForBook returns every tag on the book's work ordered by votes, marking
+// the ones viewerID voted for.
+func (r *TagRepository) GetForBook(bookID int, viewerID *int) ([]models.BookTag, error) {
+	workID, err := bookWorkID(r.db, bookID)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT t.name, COUNT(*) AS votes, COALESCE(BOOL_OR(tv.user_id = $2), false) AS voted
+		FROM tag_votes tv
+		JOIN tags t ON t.id = tv.tag_id
+		WHERE tv.work_id = $1
+		GROUP BY t.name
+		ORDER BY votes DESC, t.name ASC`
+
+	rows, err := r.db.Query(query, workID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.BookTag{}
+	for rows.Next() {
+		var tag models.BookTag
+		if err := rows.Scan(&tag.Name, &tag.Votes, &tag.Voted); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// Vote applies a tag to a book for userID, creating the tag if it's new.
+// name must already be normalized.
+func (r *TagRepository) Vote(bookID, userID int, name string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	workID, err := bookWorkID(tx, bookID)
+	if err != nil {
+		return err
+	}
+
+	var onWork, lastHour int
+	err = tx.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE work_id = $2),
+			COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '1 hour')
+		FROM tag_votes
+		WHERE user_id = $1`, userID, workID).Scan(&onWork, &lastHour)
+	if err != nil {
+		return err
+	}
+	if onWork >= maxTagsPerUserPerWork {
+		return fmt.Errorf("%w: at most %d tags per book", models.ErrTagLimit, maxTagsPerUserPerWork)
+	}
+	if lastHour >= maxTagVotesPerUserPerHour {
+		return fmt.Errorf("%w: too many tags in the last hour", models.ErrTagLimit)
+	}
+
+	var tagID int
+	err = tx.QueryRow(`SELECT id FROM tags WHERE name = $1`, name).Scan(&tagID)
+	if err == sql.ErrNoRows {
+		var createdToday int
+		err = tx.QueryRow(`SELECT COUNT(*) FROM tags WHERE created_by = $1 AND created_at > NOW() - INTERVAL '1 day'`, userID).Scan(&createdToday)
+		if err != nil {
+			return err
+		}
+		if createdToday >= maxNewTagsPerUserPerDay {
+			return fmt.Errorf("%w: too many new tags today, try an existing one", models.ErrTagLimit)
+		}
+		// Another user may create the same tag concurrently
+		err = tx.QueryRow(`
+			INSERT INTO tags (name, created_by) VALUES ($1, $2)
+			ON CONFLICT (name) DO UPDATE SET name = tags.name
+			RETURNING id`, name, userID).Scan(&tagID)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO tag_votes (work_id, tag_id, user_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		workID, tagID, userID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *TagRepository) Unvote(bookID, userID int, name string) error {
+	workID, err := bookWorkID(r.db, bookID)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.Exec(`
+		DELETE FROM tag_votes tv
+		USING tags t
+		WHERE tv.tag_id = t.id AND tv.work_id = $1 AND tv.user_id = $2 AND t.name = $3`, workID, userID, name)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Popular lists tags by how many books they're on, optionally filtered by prefix.
+func (r *TagRepository) Popular(prefix string, limit int) ([]models.Tag, error) {
+	query := `
+		SELECT t.id, t.name, COUNT(DISTINCT tv.work_id) AS use_count, t.created_at
+		FROM tags t
+		JOIN tag_votes tv ON tv.tag_id = t.id
+		WHERE $1 = '' OR t.name LIKE $1 || '%'
+		GROUP BY t.id
+		ORDER BY use_count DESC, t.name ASC
+		LIMIT $2`
+
+	rows, err := r.db.Query(query, escapeLike(prefix), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.UseCount, &tag.CreatedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func bookWorkID(q queryRower, bookID int) (int, error) {
+	var workID int
+	err := q.QueryRow(`SELECT work_id FROM books WHERE id = $1`, bookID).Scan(&workID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("book not found")
+	}
+	return workID, err
+}
+
+// topTagsForWorks returns up to perWork of the most voted tags for each work.
+func topTagsForWorks(db *sql.DB, workIDs []int, perWork int) (map[int][]models.BookTag, error) {
+	tags := make(map[int][]models.BookTag, len(workIDs))
+	if len(workIDs) == 0 {
+		return tags, nil
+	}
+	query := `
+		SELECT work_id, name, votes FROM (
+			SELECT tv.work_id, t.name, COUNT(*) AS votes,
+			       ROW_NUMBER() OVER (PARTITION BY tv.work_id ORDER BY COUNT(*) DESC, t.name) AS rn
+			FROM tag_votes tv
+			JOIN tags t ON t.id = tv.tag_id
+			WHERE tv.work_id = ANY($1)
+			GROUP BY tv.work_id, t.name
+		) ranked
+		WHERE rn <= $2
+		ORDER BY work_id, votes DESC, name`
+
+	rows, err := db.Query(query, pq.Array(workIDs), perWork)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workID int
+		var tag models.BookTag
+		if err := rows.Scan(&workID, &tag.Name, &tag.Votes); err != nil {
+			return nil, err
+		}
+		tags[workID] = append(tags[workID], tag)
+	}
+	return tags, rows.Err()
+}
@@ -19,13 +19,14 @@ func (r *UserRepository) Create(email, username, passwordHash string) (*models.U
 	query := `
 		INSERT INTO users (email, username, password_hash)
 		VALUES ($1, $2, $3)
-		RETURNING id, email, username, created_at, updated_at
+		RETURNING id, email, username, role, created_at, updated_at
 	`
 	user := &models.User{}
 	err := r.db.QueryRow(query, email, username, passwordHash).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Username,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -49,7 +50,7 @@ func (r *UserRepository) Create(email, username, passwordHash string) (*models.U
 
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	query := `
-		SELECT id, email, username, password_hash, google_id, created_at, updated_at
+		SELECT id, email, username, password_hash, google_id, role, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
@@ -64,6 +65,7 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 		&user.Username,
 		&passwordNull,
 		&googleIDNull,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -83,19 +85,27 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 }
 
 func (r *UserRepository) GetByID(userID int) (*models.User, error) {
-	query := `SELECT id, email, username, created_at, updated_at
+	query := `SELECT id, email, username, role, created_at, updated_at
 FROM users
 WHERE id = $1`
 
 	user := &models.User{}
-	err := r.db.QueryRow(query, userID).Scan(&user.ID, &user.Email, &user.Username, &user.CreatedAt, &user.UpdatedAt)
+	err := r.db.QueryRow(query, userID).Scan(&user.ID, &user.Email, &user.Username, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, sql.ErrNoRows
 	}
 	return user, nil
 }
 
+// GetProfile returns a user's profile as the viewer sees it: counts, current
+// reads and shelf statuses only cover the entries the viewer may see.
 func (r *UserRepository) GetProfile(userID int, viewerID *int) (*models.UserProfile, error) {
+	visible := visibleTo("")
+	args := []interface{}{userID}
+	if viewerID != nil {
+		visible = visibleTo("$2")
+		args = append(args, *viewerID)
+	}
 	query := `
     SELECT
         u.id, u.email, u.username, u.created_at, u.updated_at,
@@ -104,15 +114,16 @@ func (r *UserRepository) GetProfile(userID int, viewerID *int) (*models.UserProf
         COUNT(DISTINCT CASE WHEN r.status = 'to_read' THEN r.id END) as to_read,
         COUNT(DISTINCT CASE WHEN r.status = 'currently_reading' THEN r.id END) as currently_reading,
         COUNT(DISTINCT CASE WHEN r.status = 'finished_reading' THEN r.id END) as finished,
+        COUNT(DISTINCT CASE WHEN r.status = 'did_not_finish' THEN r.id END) as did_not_finish,
         (SELECT COUNT(*) FROM follows WHERE following_id = u.id) as followers_count,
         (SELECT COUNT(*) FROM follows WHERE follower_id = u.id) as following_count
     FROM users u
-    LEFT JOIN ratings r ON u.id = r.user_id
+    LEFT JOIN ratings r ON u.id = r.user_id AND ` + visible + `
     WHERE u.id = $1
     GROUP BY u.id`
 
 	profile := &models.UserProfile{}
-	err := r.db.QueryRow(query, userID).Scan(
+	err := r.db.QueryRow(query, args...).Scan(
 		&profile.ID,
 		&profile.Email,
 		&profile.Username,
@@ -123,6 +134,7 @@ func (r *UserRepository) GetProfile(userID int, viewerID *int) (*models.UserProf
 		&profile.ToReadCount,
 		&profile.CurrentlyReadingCount,
 		&profile.FinishedReadingCount,
+		&profile.DidNotFinishCount,
 		&profile.FollowersCount,
 		&profile.FollowingCount,
 	)
@@ -136,12 +148,21 @@ func (r *UserRepository) GetProfile(userID int, viewerID *int) (*models.UserProf
 		r.db.QueryRow(followQuery, *viewerID, userID).Scan(&profile.IsFollowing)
 	}
 
+	profile.CurrentReads, err = currentReads(r.db, userID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	profile.Shelves, err = userShelves(r.db, userID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
 	return profile, nil
 }
 
 func (r *UserRepository) GetByGoogleID(googleID string) (*models.User, error) {
 	query := `
-		SELECT id, email, username, password_hash, google_id, created_at, updated_at
+		SELECT id, email, username, password_hash, google_id, role, created_at, updated_at
 		FROM users
 		WHERE google_id = $1
 	`
@@ -155,6 +176,7 @@ func (r *UserRepository) GetByGoogleID(googleID string) (*models.User, error) {
 		&user.Username,
 		&passwordNull,
 		&googleIDStr,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -174,13 +196,14 @@ func (r *UserRepository) GetByGoogleID(googleID string) (*models.User, error) {
 }
 
 func (r *UserRepository) CreateWithGoogle(email, username, googleID string) (*models.User, error) {
-	query := `INSERT INTO users (email, username, google_id) VALUES ($1, $2, $3) RETURNING id, email, username, google_id, created_at, updated_at`
+	query := `INSERT INTO users (email, username, google_id) VALUES ($1, $2, $3) RETURNING id, email, username, google_id, role, created_at, updated_at`
 	user := &models.User{}
 	err := r.db.QueryRow(query, email, username, googleID).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Username,
 		&user.GoogleID,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -205,3 +228,51 @@ func (r *UserRepository) LinkGoogleAccount(userID int, googleID string) error {
 	_, err := r.db.Exec(query, googleID, userID)
 	return err
 }
+
+// GetRole returns the user's current role.
+func (r *UserRepository) GetRole(userID int) (string, error) {
+	var role string
+	err := r.db.QueryRow(`SELECT role FROM users WHERE id = $1`, userID).Scan(&role)
+	return role, err
+}
+
+func (r *UserRepository) SetRole(userID int, role string) error {
+	query := `UPDATE users SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	result, err := r.db.Exec(query, role, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *UserRepository) GetPreferences(userID int) (*models.UserPreferences, error) {
+	prefs := &models.UserPreferences{}
+	err := r.db.QueryRow(`SELECT reveal_finished_spoilers FROM users WHERE id = $1`, userID).Scan(&prefs.RevealFinishedSpoilers)
+	if err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+func (r *UserRepository) UpdatePreferences(userID int, prefs *models.UserPreferences) error {
+	query := `UPDATE users SET reveal_finished_spoilers = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	result, err := r.db.Exec(query, prefs.RevealFinishedSpoilers, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/pulkyeet/BookmarkD/internal/database"
+	"github.com/pulkyeet/BookmarkD/internal/middleware"
+)
+
+type AuthorHandler struct {
+	authorRepo *database.AuthorRepository
+	ratingRepo *database.RatingRepository
+}
+
+func NewAuthorHandler(authorRepo *database.AuthorRepository, ratingRepo *database.RatingRepository) *AuthorHandler {
+	return &AuthorHandler{authorRepo: authorRepo, ratingRepo: ratingRepo}
+}
+
+func (h *AuthorHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	authorID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid author ID", http.StatusBadRequest)
+		return
+	}
+	author, err := h.authorRepo.GetByID(authorID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Author not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Get author error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	author.AverageRating, author.RatingCount, author.ReaderCount, err = h.ratingRepo.GetAuthorStats(authorID)
+	if err != nil {
+		log.Printf("Get author stats error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if claims, ok := middleware.GetUserFromContext(r); ok {
+		author.ViewerShelves, err = h.ratingRepo.GetStatusesForAuthor(claims.UserID, authorID)
+		if err != nil {
+			log.Printf("Get author shelves error: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(author)
+}
+
+func (h *AuthorHandler) GetBooks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	authorID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid author ID", http.StatusBadRequest)
+		return
+	}
+	sortBy := r.URL.Query().Get("sort")
+	validSorts := map[string]bool{
+		"year":       true,
+		"year_desc":  true,
+		"popularity": true,
+	}
+	if sortBy != "" && !validSorts[sortBy] {
+		http.Error(w, "Invalid sort parameter", http.StatusBadRequest)
+		return
+	}
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	offset := 0
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	books, err := h.authorRepo.GetBooks(authorID, sortBy, limit, offset)
+	if err != nil {
+		log.Printf("Get author books error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if claims, ok := middleware.GetUserFromContext(r); ok {
+		bookIDs := make([]int, 0, len(books))
+		for _, b := range books {
+			bookIDs = append(bookIDs, b.BookID)
+		}
+		statuses, err := h.ratingRepo.GetStatusesForBooks(claims.UserID, bookIDs)
+		if err != nil {
+			log.Printf("Get author book statuses error: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		for i := range books {
+			books[i].ViewerStatus = statuses[books[i].BookID]
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(books)
+}
@@ -2,21 +2,24 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/pulkyeet/BookmarkD/internal/database"
 	"github.com/pulkyeet/BookmarkD/internal/models"
 	"log"
 	"github.com/pulkyeet/BookmarkD/internal/cache"
+	"github.com/pulkyeet/BookmarkD/internal/middleware"
 	"net/http"
 	"strconv"
 	"strings"
 )
 
 type BookHandler struct {
-	bookRepo *database.BookRepository
+	bookRepo        *database.BookRepository
+	pendingEditRepo *database.PendingEditRepository
 }
 
-func NewBookHandler(bookRepo *database.BookRepository) *BookHandler {
-	return &BookHandler{bookRepo: bookRepo}
+func NewBookHandler(bookRepo *database.BookRepository, pendingEditRepo *database.PendingEditRepository) *BookHandler {
+	return &BookHandler{bookRepo: bookRepo, pendingEditRepo: pendingEditRepo}
 }
 
 func (h *BookHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -29,12 +32,34 @@ func (h *BookHandler) Create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
-	if req.Title == "" || req.Author == "" {
+	if req.Title == "" || len(models.NormaliseAuthorCredits(req.Authors, req.Author)) == 0 {
 		http.Error(w, "Title and Author are required", http.StatusBadRequest)
 		return
 	}
+	if !validAuthorRoles(req.Authors) {
+		http.Error(w, "Invalid author role", http.StatusBadRequest)
+		return
+	}
+	if req.PageCount < 0 {
+		http.Error(w, "Page count can't be negative", http.StatusBadRequest)
+		return
+	}
+	isbn, err := models.NormalizeISBN(req.ISBN)
+	if err != nil {
+		http.Error(w, "Invalid ISBN", http.StatusBadRequest)
+		return
+	}
+	req.ISBN = isbn
 	book, err := h.bookRepo.Create(req)
 	if err != nil {
+		if err.Error() == "work not found" {
+			http.Error(w, "Work not found", http.StatusBadRequest)
+			return
+		}
+		if err == models.ErrISBNExists {
+			http.Error(w, "A book with this ISBN already exists", http.StatusConflict)
+			return
+		}
 		log.Printf("Create book error: %v", err)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
@@ -75,39 +100,149 @@ func (h *BookHandler) Get(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(book)
 }
 
+func (h *BookHandler) GetByISBN(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Served from the /api/books/ prefix route; a {isbn} pattern would clash with /api/books/{id}/...
+	isbn, err := models.NormalizeISBN(strings.TrimPrefix(r.URL.Path, "/api/books/isbn/"))
+	if err != nil || isbn == "" {
+		http.Error(w, "Invalid ISBN", http.StatusBadRequest)
+		return
+	}
+	book, err := h.bookRepo.GetByISBN(isbn)
+	if err != nil {
+		if err.Error() == "book not found" {
+			http.Error(w, "Book not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Get book by ISBN error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(book)
+}
+
 func (h *BookHandler) List(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	// Parse query params
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-	search := r.URL.Query().Get("search")
-	genre := r.URL.Query().Get("genre")
+	page, err := parsePage(r, 20, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
 
-	limit := 20
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
+	// Repeated tag= params must all match
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, raw := range r.URL.Query()["tag"] {
+		tag, err := models.NormalizeTagName(raw)
+		if err != nil {
+			http.Error(w, "Invalid tag filter", http.StatusBadRequest)
+			return
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
 		}
 	}
 
-	offset := 0
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
+	filter, err := parseBookFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Tags = tags
+	if r.URL.Query().Get("exclude_shelved") == "true" {
+		claims, ok := middleware.GetUserFromContext(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
 		}
+		filter.ExcludeShelvedBy = &claims.UserID
 	}
 
-	books, err := h.bookRepo.ListWithGenres(limit, offset, search, genre)
+	books, next, err := h.bookRepo.ListWithGenres(page, filter)
 	if err != nil {
 		log.Printf("List books error: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	if r.URL.Query().Get("facets") != "true" && !usesCursor(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(books)
+		return
+	}
+
+	resp := models.BookListResponse{Books: books, NextCursor: next}
+	if r.URL.Query().Get("facets") == "true" {
+		resp.Facets, err = h.bookRepo.Facets(filter)
+		if err != nil {
+			log.Printf("Book facets error: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(books)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// parseBookFilter reads the search, genre, year and rating filters from the query string.
+func parseBookFilter(r *http.Request) (models.BookFilter, error) {
+	q := r.URL.Query()
+	filter := models.BookFilter{Search: q.Get("search")}
+
+	for _, genre := range q["genre"] {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			filter.Genres = append(filter.Genres, genre)
+		}
+	}
+	switch q.Get("genre_mode") {
+	case "", "or":
+	case "and":
+		filter.MatchAllGenres = true
+	default:
+		return filter, fmt.Errorf("genre_mode must be 'and' or 'or'")
+	}
+
+	if raw := q.Get("year_from"); raw != "" {
+		year, err := strconv.Atoi(raw)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid year_from")
+		}
+		filter.YearFrom = &year
+	}
+	if raw := q.Get("year_to"); raw != "" {
+		year, err := strconv.Atoi(raw)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid year_to")
+		}
+		filter.YearTo = &year
+	}
+	if filter.YearFrom != nil && filter.YearTo != nil && *filter.YearFrom > *filter.YearTo {
+		return filter, fmt.Errorf("year_from must not be after year_to")
+	}
+
+	if raw := q.Get("min_rating"); raw != "" {
+		minAverage, err := strconv.ParseFloat(raw, 64)
+		if err != nil || minAverage < 0 || minAverage > 10 {
+			return filter, fmt.Errorf("min_rating must be between 0 and 10")
+		}
+		filter.MinAverage = &minAverage
+	}
+	if raw := q.Get("min_ratings"); raw != "" {
+		count, err := strconv.Atoi(raw)
+		if err != nil || count < 0 {
+			return filter, fmt.Errorf("Invalid min_ratings")
+		}
+		filter.MinRatingCount = count
+	}
+
+	return filter, nil
 }
 
 func (h *BookHandler) Update(w http.ResponseWriter, r *http.Request) {
@@ -130,12 +265,58 @@ func (h *BookHandler) Update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-	book, err := h.bookRepo.Update(id, req)
+	if req.Authors != nil {
+		if len(models.NormaliseAuthorCredits(*req.Authors, "")) == 0 {
+			http.Error(w, "At least one author is required", http.StatusBadRequest)
+			return
+		}
+		if !validAuthorRoles(*req.Authors) {
+			http.Error(w, "Invalid author role", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.ISBN != nil {
+		isbn, err := models.NormalizeISBN(*req.ISBN)
+		if err != nil {
+			http.Error(w, "Invalid ISBN", http.StatusBadRequest)
+			return
+		}
+		req.ISBN = &isbn
+	}
+	if req.PageCount != nil && *req.PageCount < 0 {
+		http.Error(w, "Page count can't be negative", http.StatusBadRequest)
+		return
+	}
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	// Users below librarian can only propose changes
+	allowed, err := middleware.HasRole(r, models.RoleLibrarian)
+	if err != nil {
+		log.Printf("Role lookup error: %v", err)
+		http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		submitPendingEdit(w, h.pendingEditRepo, id, claims.UserID, models.EditActionUpdate, &req)
+		return
+	}
+	book, err := h.bookRepo.Update(id, req, claims.UserID)
 	if err != nil {
 		if err.Error() == "book not found" {
 			http.Error(w, "Book not found", http.StatusNotFound)
 			return
 		}
+		if err.Error() == "work not found" {
+			http.Error(w, "Work not found", http.StatusBadRequest)
+			return
+		}
+		if err == models.ErrISBNExists {
+			http.Error(w, "A book with this ISBN already exists", http.StatusConflict)
+			return
+		}
 		log.Printf("Update book error: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -160,6 +341,21 @@ func (h *BookHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid book ID", http.StatusBadRequest)
 		return
 	}
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	allowed, err := middleware.HasRole(r, models.RoleModerator)
+	if err != nil {
+		log.Printf("Role lookup error: %v", err)
+		http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		submitPendingEdit(w, h.pendingEditRepo, id, claims.UserID, models.EditActionDelete, nil)
+		return
+	}
 	err = h.bookRepo.Delete(id)
 	if err != nil {
 		if err.Error() == "book not found" {
@@ -262,3 +458,93 @@ func (h *BookHandler) GetPopular(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(books)
 }
+
+func (h *BookHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+	revisions, err := h.bookRepo.GetRevisions(bookID, limit, offset)
+	if err != nil && err.Error() == "book not found" {
+		http.Error(w, "Book not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Get book history error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+func (h *BookHandler) Revert(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	revisionID, err := strconv.Atoi(r.PathValue("revisionID"))
+	if err != nil {
+		http.Error(w, "Invalid revision ID", http.StatusBadRequest)
+		return
+	}
+	book, err := h.bookRepo.RevertRevision(bookID, revisionID, claims.UserID)
+	if err != nil {
+		switch err.Error() {
+		case "revision not found":
+			http.Error(w, "Revision not found", http.StatusNotFound)
+		case "book not found":
+			http.Error(w, "Book not found", http.StatusNotFound)
+		case "work not found":
+			http.Error(w, "Work from this revision no longer exists", http.StatusConflict)
+		default:
+			log.Printf("Revert book error: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+	cache.InvalidateBookCache(strconv.Itoa(bookID))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(book)
+}
+
+// submitPendingEdit queues a change for moderator review and responds with 202.
+func submitPendingEdit(w http.ResponseWriter, pendingEditRepo *database.PendingEditRepository, bookID, userID int, action string, changes *models.UpdateBookRequest) {
+	edit, err := pendingEditRepo.Submit(bookID, userID, action, changes)
+	if err != nil {
+		if err.Error() == "book not found" {
+			http.Error(w, "Book not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Submit pending edit error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(edit)
+}
+
+func validAuthorRoles(credits []models.AuthorCredit) bool {
+	for _, c := range credits {
+		if c.Role != "" && !models.ValidAuthorRoles[c.Role] {
+			return false
+		}
+	}
+	return true
+}
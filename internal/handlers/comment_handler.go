@@ -56,14 +56,18 @@ func (h *CommentHandler) GetComments(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid Rating ID", http.StatusBadRequest)
 		return
 	}
-	comments, err := h.commentRepo.GetByRatingID(ratingID)
+	page, err := parsePage(r, 0, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	comments, next, err := h.commentRepo.GetByRatingID(ratingID, page)
 	if err != nil {
 		log.Printf("Error getting comments: %v", err)
 		http.Error(w, "Failed to get comments", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(comments)
+	writePage(w, r, comments, next)
 }
 
 func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
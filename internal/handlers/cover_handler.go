@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pulkyeet/BookmarkD/internal/cache"
+	"github.com/pulkyeet/BookmarkD/internal/covers"
+	"github.com/pulkyeet/BookmarkD/internal/database"
+	"github.com/pulkyeet/BookmarkD/internal/middleware"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+	"github.com/pulkyeet/BookmarkD/internal/storage"
+)
+
+type CoverHandler struct {
+	coverService    *covers.Service
+	bookRepo        *database.BookRepository
+	pendingEditRepo *database.PendingEditRepository
+}
+
+func NewCoverHandler(coverService *covers.Service, bookRepo *database.BookRepository, pendingEditRepo *database.PendingEditRepository) *CoverHandler {
+	return &CoverHandler{coverService: coverService, bookRepo: bookRepo, pendingEditRepo: pendingEditRepo}
+}
+
+// Upload accepts a multipart "cover" file. Librarians and above update the
+// book straight away; anyone else's upload is stored and queued as a pending
+// edit, and discarded again if the edit is rejected.
+func (h *CoverHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.bookRepo.GetByID(bookID); err != nil {
+		if err.Error() == "book not found" {
+			http.Error(w, "Book not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Get book error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	allowed, err := middleware.HasRole(r, models.RoleLibrarian)
+	if err != nil {
+		log.Printf("Role lookup error: %v", err)
+		http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, covers.MaxUploadBytes+1<<20)
+	if err := r.ParseMultipartForm(covers.MaxUploadBytes); err != nil {
+		http.Error(w, "Cover must be an image under 5MB", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("cover")
+	if err != nil {
+		http.Error(w, "Missing cover file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, covers.MaxUploadBytes+1))
+	if err != nil {
+		http.Error(w, "Failed to read cover", http.StatusBadRequest)
+		return
+	}
+	if len(data) > covers.MaxUploadBytes {
+		http.Error(w, "Cover must be an image under 5MB", http.StatusBadRequest)
+		return
+	}
+
+	coverURL, err := h.coverService.Save(data)
+	if err == covers.ErrUnsupportedImage {
+		http.Error(w, "Cover must be a JPEG, PNG or GIF image", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Save cover error: %v", err)
+		http.Error(w, "Failed to save cover", http.StatusInternalServerError)
+		return
+	}
+
+	req := models.UpdateBookRequest{CoverURL: &coverURL}
+	if !allowed {
+		submitPendingEdit(w, h.pendingEditRepo, bookID, claims.UserID, models.EditActionUpdate, &req)
+		return
+	}
+	book, err := h.bookRepo.Update(bookID, req, claims.UserID)
+	if err != nil {
+		if err.Error() == "book not found" {
+			http.Error(w, "Book not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Update book cover error: %v", err)
+		http.Error(w, "Failed to update cover", http.StatusInternalServerError)
+		return
+	}
+	cache.InvalidateBookCache(strconv.Itoa(bookID))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(book)
+}
+
+// Serve streams a stored cover. Cover keys contain a content hash, so the
+// response can be cached forever.
+func (h *CoverHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, models.CoverURLPrefix)
+	blob, err := h.coverService.Store().Open(key)
+	if err == storage.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Open cover error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", `"`+strings.ReplaceAll(key, "/", "-")+`"`)
+	http.ServeContent(w, r, "", blob.ModTime(), blob)
+}
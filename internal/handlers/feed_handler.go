@@ -1,12 +1,10 @@
 package handlers
 
 import (
-	"encoding/json"
 	"github.com/pulkyeet/BookmarkD/internal/database"
 	"github.com/pulkyeet/BookmarkD/internal/middleware"
 	"log"
 	"net/http"
-	"strconv"
 )
 
 type FeedHandler struct {
@@ -28,26 +26,18 @@ func (h *FeedHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
 		feedType = "all"
 	}
 
-	limit := 20
-	offset := 0
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
-	}
-	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
+	page, err := parsePage(r, 20, 0)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
 	}
 
-	items, err := h.ratingRepo.GetFeedByType(userID, feedType, limit, offset)
+	items, next, err := h.ratingRepo.GetFeedByType(userID, feedType, page)
 	if err != nil {
 		log.Printf("Error getting feed: %v", err)
 		http.Error(w, "Failed to get feed", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(items)
+	writePage(w, r, items, next)
 }
\ No newline at end of file
@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
-	"github.com/pulkyeet/BookmarkD/internal/database"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pulkyeet/BookmarkD/internal/cache"
+	"github.com/pulkyeet/BookmarkD/internal/database"
+	"github.com/pulkyeet/BookmarkD/internal/models"
 )
 
 type GenreHandler struct {
@@ -29,3 +35,171 @@ func (h *GenreHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(genres)
 }
+
+func (h *GenreHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateGenreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" || len(req.Name) > 100 {
+		http.Error(w, "Genre name must be 1-100 characters", http.StatusBadRequest)
+		return
+	}
+	genre, err := h.genreRepo.Create(req)
+	if err != nil {
+		h.writeError(w, "Create genre", err)
+		return
+	}
+	invalidateGenreCache()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(genre)
+}
+
+func (h *GenreHandler) Update(w http.ResponseWriter, r *http.Request) {
+	genreID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid genre ID", http.StatusBadRequest)
+		return
+	}
+	var req models.UpdateGenreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name != nil && (strings.TrimSpace(*req.Name) == "" || len(*req.Name) > 100) {
+		http.Error(w, "Genre name must be 1-100 characters", http.StatusBadRequest)
+		return
+	}
+	genre, err := h.genreRepo.Update(genreID, req)
+	if err != nil {
+		h.writeError(w, "Update genre", err)
+		return
+	}
+	invalidateGenreCache()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(genre)
+}
+
+func (h *GenreHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	genreID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid genre ID", http.StatusBadRequest)
+		return
+	}
+	if err := h.genreRepo.Delete(genreID); err != nil {
+		h.writeError(w, "Delete genre", err)
+		return
+	}
+	invalidateGenreCache()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *GenreHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	sourceID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid genre ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		IntoID int `json:"into_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.IntoID <= 0 || req.IntoID == sourceID {
+		http.Error(w, "into_id must be a different genre", http.StatusBadRequest)
+		return
+	}
+	genre, err := h.genreRepo.Merge(sourceID, req.IntoID)
+	if err != nil {
+		h.writeError(w, "Merge genre", err)
+		return
+	}
+	invalidateGenreCache()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(genre)
+}
+
+func (h *GenreHandler) AssignToBook(w http.ResponseWriter, r *http.Request) {
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		GenreID int `json:"genre_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.genreRepo.AddGenreToBook(bookID, req.GenreID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Book or genre not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Assign genre error: %v", err)
+		http.Error(w, "Failed to assign genre", http.StatusInternalServerError)
+		return
+	}
+	h.writeBookGenres(w, bookID)
+}
+
+func (h *GenreHandler) UnassignFromBook(w http.ResponseWriter, r *http.Request) {
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	genreID, err := strconv.Atoi(r.PathValue("genreID"))
+	if err != nil {
+		http.Error(w, "Invalid genre ID", http.StatusBadRequest)
+		return
+	}
+	if err := h.genreRepo.RemoveGenreFromBook(bookID, genreID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Genre not assigned to this book", http.StatusNotFound)
+			return
+		}
+		log.Printf("Unassign genre error: %v", err)
+		http.Error(w, "Failed to unassign genre", http.StatusInternalServerError)
+		return
+	}
+	h.writeBookGenres(w, bookID)
+}
+
+func (h *GenreHandler) writeBookGenres(w http.ResponseWriter, bookID int) {
+	cache.InvalidateBookCache(strconv.Itoa(bookID))
+	genres, err := h.genreRepo.GetByBookID(bookID)
+	if err != nil {
+		log.Printf("Get book genres error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(genres)
+}
+
+func (h *GenreHandler) writeError(w http.ResponseWriter, action string, err error) {
+	switch err {
+	case sql.ErrNoRows:
+		http.Error(w, "Genre not found", http.StatusNotFound)
+	case models.ErrGenreExists:
+		http.Error(w, "A genre with this name already exists", http.StatusConflict)
+	case models.ErrGenreCycle:
+		http.Error(w, "A genre cannot be nested under itself or its subgenres", http.StatusBadRequest)
+	default:
+		log.Printf("%s error: %v", action, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// invalidateGenreCache drops the cached genre list and any book listings
+// filtered by genre, since renames and merges change what they match.
+func invalidateGenreCache() {
+	cache.Delete(cache.GenerateKey("/api/genres"))
+	cache.DeletePattern("cache:global:/api/books*")
+}
@@ -4,8 +4,10 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 	"github.com/pulkyeet/BookmarkD/internal/database"
 	"github.com/pulkyeet/BookmarkD/internal/middleware"
 	"github.com/pulkyeet/BookmarkD/internal/models"
@@ -14,19 +16,22 @@ import (
 type ImportHandler struct {
 	bookRepo *database.BookRepository
 	ratingRepo *database.RatingRepository
+	shelfRepo *database.ShelfRepository
 }
 
 type ImportResult struct {
 	BookImported int `json:"books_imported"`
 	RatingsImported int `json:"ratings_imported"`
+	ShelvedImported int `json:"shelved_imported"`
 	Skipped int `json:"skipped"`
 	Errors []string `json:"errors"`
 }
 
-func NewImportHandler(bookRepo *database.BookRepository, ratingRepo *database.RatingRepository) *ImportHandler {
+func NewImportHandler(bookRepo *database.BookRepository, ratingRepo *database.RatingRepository, shelfRepo *database.ShelfRepository) *ImportHandler {
 	return &ImportHandler{
 		bookRepo: bookRepo,
 		ratingRepo: ratingRepo,
+		shelfRepo: shelfRepo,
 	}
 }
 
@@ -65,9 +70,15 @@ func (h *ImportHandler) ImportGoodreads(w http.ResponseWriter, r *http.Request)
 	header := records[0]
 	titleIdx := findColumn(header, "Title")
 	authorIdx := findColumn(header, "Author")
+	additionalAuthorsIdx := findColumn(header, "Additional Authors")
 	isbnIdx := findColumn(header, "ISBN13")
+	isbn10Idx := findExactColumn(header, "ISBN")
 	ratingIdx := findColumn(header, "My Rating")
 	shelfIdx := findColumn(header, "Exclusive Shelf")
+	dateReadIdx := findColumn(header, "Date Read")
+	privateNotesIdx := findColumn(header, "Private Notes")
+	// "Bookshelves with positions" would also match a partial search
+	bookshelvesIdx := findExactColumn(header, "Bookshelves")
 	
 	if titleIdx == -1 || authorIdx == -1 {
 		http.Error(w, "CSV missing required columns(title, author)", http.StatusBadRequest)
@@ -87,15 +98,31 @@ func (h *ImportHandler) ImportGoodreads(w http.ResponseWriter, r *http.Request)
 			result.Skipped++
 			continue
 		}
-		book, err := h.bookRepo.FindByTitleAuthor(title, author)
+		// Goodreads wraps ISBNs as ="978..."; unusable values are dropped rather than failing the row
+		isbn := ""
+		for _, idx := range []int{isbnIdx, isbn10Idx} {
+			if isbn == "" && idx != -1 && len(record) > idx {
+				isbn, _ = models.NormalizeISBN(record[idx])
+			}
+		}
+		var book *models.Book
+		if isbn != "" {
+			book, err = h.bookRepo.GetByISBN(isbn)
+		}
+		if book == nil {
+			book, err = h.bookRepo.FindByTitleAuthor(title, author)
+		}
 		if err != nil {
-			isbn := ""
-			if isbnIdx!= -1 && len(record) > isbnIdx {
-				isbn = strings.TrimSpace(record[isbnIdx])
+			credits := []models.AuthorCredit{{Name: author}}
+			if additionalAuthorsIdx != -1 && len(record) > additionalAuthorsIdx {
+				for _, name := range strings.Split(record[additionalAuthorsIdx], ",") {
+					credits = append(credits, models.AuthorCredit{Name: name})
+				}
 			}
 			book, err = h.bookRepo.Create(models.CreateBookRequest{
 				Title: title,
 				Author: author,
+				Authors: credits,
 				ISBN: isbn,
 			})
 			if err != nil {
@@ -114,17 +141,67 @@ func (h *ImportHandler) ImportGoodreads(w http.ResponseWriter, r *http.Request)
 				}
 			}
 		}
+		// Goodreads writes Date Read as YYYY/MM/DD; without it the read is finished on an unknown date
+		var dateRead *time.Time
+		if dateReadIdx != -1 && len(record) > dateReadIdx {
+			dateRead, _ = models.ParseReadDate(record[dateReadIdx])
+		}
 		status := "finished_reading"
+		customShelves := []string{}
+		shelved := false
 		if shelfIdx != -1 && len(record) > shelfIdx {
 			shelf := strings.ToLower(strings.TrimSpace(record[shelfIdx]))
-			switch shelf {
-				case "to-read": status = "to-read"
-				case "currently-reading": status = "currently_reading"
-				default: status = "finished_reading"
+			shelved = shelf != ""
+			if shelfStatus, ok := models.StatusForShelf(shelf); ok {
+				status = shelfStatus
+			} else if shelf != "" {
+				// A custom exclusive shelf says nothing about whether the book was read
+				customShelves = append(customShelves, shelf)
+				if dateRead == nil {
+					status = "to_read"
+				}
+			}
+		}
+		if bookshelvesIdx != -1 && len(record) > bookshelvesIdx {
+			for _, shelf := range strings.Split(record[bookshelvesIdx], ",") {
+				shelf = strings.ToLower(strings.TrimSpace(shelf))
+				if _, ok := models.StatusForShelf(shelf); ok || shelf == "" || slices.Contains(customShelves, shelf) {
+					continue
+				}
+				customShelves = append(customShelves, shelf)
+			}
+		}
+		for _, shelf := range customShelves {
+			name, err := models.NormalizeShelfName(shelf)
+			if err != nil {
+				continue
 			}
+			shelfID, err := h.shelfRepo.FindOrCreate(claims.UserID, name)
+			if err == nil {
+				err = h.shelfRepo.AddBook(shelfID, book.ID)
+			}
+			if err != nil {
+				result.Errors = append(result.Errors, "Row "+strconv.Itoa(i+2)+": Failed to add to shelf "+name+" - "+title)
+				continue
+			}
+			result.ShelvedImported++
 		}
-		if ratingVal > 0 {
-			_, err = h.ratingRepo.Upsert(claims.UserID, book.ID, ratingVal, "", status)
+		var dates models.ReadDates
+		if status == "finished_reading" {
+			dates.FinishedAt = dateRead
+			dates.FinishedUndated = dateRead == nil
+		}
+		// Unrated books are still worth importing when the export says where they're
+		// shelved or when they were read
+		if ratingVal > 0 || shelved || dateRead != nil {
+			// Goodreads private notes carry over as private notes; visibility is left as is
+			var privacy models.EntryPrivacy
+			if privateNotesIdx != -1 && len(record) > privateNotesIdx {
+				if notes := strings.TrimSpace(record[privateNotesIdx]); notes != "" {
+					privacy.PrivateNotes = &notes
+				}
+			}
+			_, err = h.ratingRepo.Upsert(claims.UserID, book.ID, ratingVal, "", false, "", status, privacy, dates)
 			if err != nil {
 				result.Errors = append(result.Errors, "Row "+strconv.Itoa(i+2)+": Failed to create rating - "+title)
 				continue
@@ -139,6 +216,17 @@ func (h *ImportHandler) ImportGoodreads(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(result)
 }
 
+// findExactColumn is like findColumn but needs the whole header to match,
+// e.g. so "ISBN" doesn't pick up "ISBN13".
+func findExactColumn(header []string, name string) int {
+	for i, col := range header {
+		if strings.EqualFold(strings.TrimSpace(col), name) {
+			return i
+		}
+	}
+	return -1
+}
+
 func findColumn(header []string, name string) int {
 	for i, col := range header {
 		if strings.Contains(strings.ToLower(col), strings.ToLower(name)) {
@@ -79,7 +79,12 @@ func (h *ListHandler) GetUserLists(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid List ID", http.StatusBadRequest)
 		return
 	}
-	lists, err := h.listRepo.GetByUserID(userID)
+	page, err := parsePage(r, 0, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	lists, next, err := h.listRepo.GetByUserID(userID, page)
 	if err == sql.ErrNoRows {
 		http.Error(w, "List not found", http.StatusNotFound)
 		return
@@ -89,8 +94,7 @@ func (h *ListHandler) GetUserLists(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to get lists", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(lists)
+	writePage(w, r, lists, next)
 }
 
 func (h *ListHandler) Update(w http.ResponseWriter, r *http.Request) {
@@ -165,7 +169,7 @@ func (h *ListHandler) AddBook(w http.ResponseWriter, r *http.Request) {
 	}
 	listID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		log.Printf("Error decoding listID:", err)
+		log.Printf("Error decoding listID: %v", err)
 		http.Error(w, "Invalid List ID", http.StatusBadRequest)
 		return
 	}
@@ -174,7 +178,7 @@ func (h *ListHandler) AddBook(w http.ResponseWriter, r *http.Request) {
 		Position int `json:"position"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("Error decoding request body:", err)
+		log.Printf("Error decoding request body: %v", err)
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
@@ -185,7 +189,7 @@ func (h *ListHandler) AddBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err != nil {
-		log.Printf("Error getting list:", err)
+		log.Printf("Error getting list: %v", err)
 		http.Error(w, "Failed to get list", http.StatusInternalServerError)
 		return
 	}
@@ -197,7 +201,7 @@ func (h *ListHandler) AddBook(w http.ResponseWriter, r *http.Request) {
 	if position == 0 {
 		position, err = h.listRepo.GetNextPosition(listID)
 		if err != nil {
-			log.Printf("Error getting next position:", err)
+			log.Printf("Error getting next position: %v", err)
 			http.Error(w, "Failed to add book", http.StatusInternalServerError)
 			return
 		}
@@ -205,7 +209,7 @@ func (h *ListHandler) AddBook(w http.ResponseWriter, r *http.Request) {
 	}
 	err = h.listRepo.AddBook(listID, req.BookID, position)
 	if err != nil {
-		log.Printf("Error adding book:", err)
+		log.Printf("Error adding book: %v", err)
 		http.Error(w, "Failed to add book", http.StatusInternalServerError)
 		return
 	}
@@ -221,13 +225,13 @@ func (h *ListHandler) RemoveBook(w http.ResponseWriter, r *http.Request) {
 	}
 	listID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		log.Printf("Error decoding listID:", err)
+		log.Printf("Error decoding listID: %v", err)
 		http.Error(w, "Invalid List ID", http.StatusBadRequest)
 		return
 	}
 	bookID, err := strconv.Atoi(r.PathValue("bookID"))
 	if err != nil {
-		log.Printf("Error decoding bookID:", err)
+		log.Printf("Error decoding bookID: %v", err)
 		http.Error(w, "Invalid Book ID", http.StatusBadRequest)
 		return
 	}
@@ -237,7 +241,7 @@ func (h *ListHandler) RemoveBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err != nil {
-		log.Printf("Error getting list:", err)
+		log.Printf("Error getting list: %v", err)
 		http.Error(w, "Failed to get list", http.StatusInternalServerError)
 		return
 	}
@@ -251,7 +255,7 @@ func (h *ListHandler) RemoveBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err != nil {
-		log.Printf("Error removing book from list", err)
+		log.Printf("Error removing book from list: %v", err)
 		http.Error(w, "Failed to remove book from list", http.StatusInternalServerError)
 		return
 	}
@@ -267,7 +271,7 @@ func (h *ListHandler) ReorderBooks(w http.ResponseWriter, r *http.Request) {
 	}
 	listID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		log.Printf("Error decoding listID:", err)
+		log.Printf("Error decoding listID: %v", err)
 		http.Error(w, "Invalid List ID", http.StatusBadRequest)
 		return
 	}
@@ -278,7 +282,7 @@ func (h *ListHandler) ReorderBooks(w http.ResponseWriter, r *http.Request) {
 		} `json:"books"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("Error decoding request body:", err)
+		log.Printf("Error decoding request body: %v", err)
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
@@ -288,7 +292,7 @@ func (h *ListHandler) ReorderBooks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err != nil {
-		log.Printf("Error getting list:", err)
+		log.Printf("Error getting list: %v", err)
 		http.Error(w, "Failed to get list", http.StatusInternalServerError)
 		return
 	}
@@ -302,7 +306,7 @@ func (h *ListHandler) ReorderBooks(w http.ResponseWriter, r *http.Request) {
 	}
 	err = h.listRepo.ReorderBooks(listID, bookPositions)
 	if err != nil {
-		log.Printf("Error reordering books from list", err)
+		log.Printf("Error reordering books from list: %v", err)
 		http.Error(w, "Failed to reorder books from list", http.StatusInternalServerError)
 		return
 	}
@@ -318,13 +322,13 @@ func (h *ListHandler) BookmarkList(w http.ResponseWriter, r *http.Request) {
 	}
 	listID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		log.Printf("Error decoding listID:", err)
+		log.Printf("Error decoding listID: %v", err)
 		http.Error(w, "Invalid List ID", http.StatusBadRequest)
 		return
 	}
 	err = h.listRepo.BookmarkList(claims.UserID, listID)
 	if err != nil {
-		log.Printf("Error bookmarking list:", err)
+		log.Printf("Error bookmarking list: %v", err)
 		http.Error(w, "Failed to bookmark list", http.StatusInternalServerError)
 		return
 	}
@@ -339,7 +343,7 @@ func (h *ListHandler) UnbookmarkList(w http.ResponseWriter, r *http.Request) {
 	}
 	listID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		log.Printf("Error decoding listID:", err)
+		log.Printf("Error decoding listID: %v", err)
 		http.Error(w, "Invalid List ID", http.StatusBadRequest)
 		return
 	}
@@ -349,7 +353,7 @@ func (h *ListHandler) UnbookmarkList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err != nil {
-		log.Printf("Error unbookmarking list:", err)
+		log.Printf("Error unbookmarking list: %v", err)
 		http.Error(w, "Failed to unbookmark list", http.StatusInternalServerError)
 		return
 	}
@@ -362,29 +366,31 @@ func (h *ListHandler) GetBookmarkedLists(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	lists, err := h.listRepo.GetBookmarkedLists(claims.UserID)
+	page, err := parsePage(r, 0, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	lists, next, err := h.listRepo.GetBookmarkedLists(claims.UserID, page)
 	if err != nil {
-		log.Printf("Error getting bookmarked lists:", err)
+		log.Printf("Error getting bookmarked lists: %v", err)
 		http.Error(w, "Failed to get lists", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(lists)
+	writePage(w, r, lists, next)
 }
 
 func (h *ListHandler) GetPopularLists(w http.ResponseWriter, r *http.Request) {
-	limit := 20
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	page, err := parsePage(r, 20, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
 	}
-	lists, err := h.listRepo.GetPopularLists(limit)
+	lists, next, err := h.listRepo.GetPopularLists(page)
 	if err != nil {
-		log.Printf("Error getting popular lists:", err)
+		log.Printf("Error getting popular lists: %v", err)
 		http.Error(w, "Failed to get lists", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(lists)
+	writePage(w, r, lists, next)
 }
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/pulkyeet/BookmarkD/internal/cache"
+	"github.com/pulkyeet/BookmarkD/internal/covers"
+	"github.com/pulkyeet/BookmarkD/internal/database"
+	"github.com/pulkyeet/BookmarkD/internal/middleware"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+type ModerationHandler struct {
+	pendingEditRepo *database.PendingEditRepository
+	bookRepo        *database.BookRepository
+	userRepo        *database.UserRepository
+	duplicateRepo   *database.DuplicateRepository
+	coverService    *covers.Service
+}
+
+func NewModerationHandler(pendingEditRepo *database.PendingEditRepository, bookRepo *database.BookRepository, userRepo *database.UserRepository, duplicateRepo *database.DuplicateRepository, coverService *covers.Service) *ModerationHandler {
+	return &ModerationHandler{pendingEditRepo: pendingEditRepo, bookRepo: bookRepo, userRepo: userRepo, duplicateRepo: duplicateRepo, coverService: coverService}
+}
+
+func (h *ModerationHandler) ListEdits(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = models.EditStatusPending
+	}
+	if status != models.EditStatusPending && status != models.EditStatusApproved && status != models.EditStatusRejected {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+	edits, err := h.pendingEditRepo.List(status, limit, offset)
+	if err != nil {
+		log.Printf("List pending edits error: %v", err)
+		http.Error(w, "Failed to get pending edits", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(edits)
+}
+
+func (h *ModerationHandler) ApproveEdit(w http.ResponseWriter, r *http.Request) {
+	edit, claims, ok := h.claimEdit(w, r, models.EditStatusApproved)
+	if !ok {
+		return
+	}
+
+	var err error
+	switch edit.Action {
+	case models.EditActionUpdate:
+		if edit.Changes == nil {
+			edit.Changes = &models.UpdateBookRequest{}
+		}
+		// The revision is credited to whoever proposed the change
+		_, err = h.bookRepo.Update(edit.BookID, *edit.Changes, edit.UserID)
+	case models.EditActionDelete:
+		err = h.bookRepo.Delete(edit.BookID)
+	}
+	if err != nil {
+		if reopenErr := h.pendingEditRepo.Reopen(edit.ID); reopenErr != nil {
+			log.Printf("Reopen pending edit error: %v", reopenErr)
+		}
+		if err.Error() == "book not found" {
+			http.Error(w, "Book not found", http.StatusNotFound)
+			return
+		}
+		if err.Error() == "work not found" {
+			http.Error(w, "Work not found", http.StatusBadRequest)
+			return
+		}
+		if err == models.ErrISBNExists {
+			http.Error(w, "A book with this ISBN already exists", http.StatusConflict)
+			return
+		}
+		log.Printf("Apply pending edit error: %v", err)
+		http.Error(w, "Failed to apply edit", http.StatusInternalServerError)
+		return
+	}
+
+	cache.InvalidateBookCache(strconv.Itoa(edit.BookID))
+	edit.Status = models.EditStatusApproved
+	edit.ReviewedBy = &claims.UserID
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(edit)
+}
+
+func (h *ModerationHandler) RejectEdit(w http.ResponseWriter, r *http.Request) {
+	edit, claims, ok := h.claimEdit(w, r, models.EditStatusRejected)
+	if !ok {
+		return
+	}
+	if edit.Changes != nil && edit.Changes.CoverURL != nil {
+		h.discardCover(*edit.Changes.CoverURL)
+	}
+	edit.Status = models.EditStatusRejected
+	edit.ReviewedBy = &claims.UserID
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(edit)
+}
+
+// discardCover removes an uploaded cover once nothing refers to it. The edit
+// is rejected either way, so failures are only logged.
+func (h *ModerationHandler) discardCover(coverURL string) {
+	inUse, err := h.pendingEditRepo.CoverInUse(coverURL)
+	if err != nil {
+		log.Printf("Check cover in use error: %v", err)
+		return
+	}
+	if inUse {
+		return
+	}
+	if err := h.coverService.Discard(coverURL); err != nil {
+		log.Printf("Discard cover error: %v", err)
+	}
+}
+
+// claimEdit loads the edit from the path and marks it reviewed so two
+// moderators can't act on the same edit. It writes the error response itself.
+func (h *ModerationHandler) claimEdit(w http.ResponseWriter, r *http.Request, status string) (*models.PendingBookEdit, *models.Claims, bool) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	editID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid edit ID", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	edit, err := h.pendingEditRepo.GetByID(editID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Edit not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	if err != nil {
+		log.Printf("Get pending edit error: %v", err)
+		http.Error(w, "Failed to get edit", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if err := h.pendingEditRepo.MarkReviewed(editID, claims.UserID, status); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Edit has already been reviewed", http.StatusConflict)
+			return nil, nil, false
+		}
+		log.Printf("Review pending edit error: %v", err)
+		http.Error(w, "Failed to review edit", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	return edit, claims, true
+}
+
+func (h *ModerationHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !models.ValidRole(req.Role) {
+		http.Error(w, "Invalid role", http.StatusBadRequest)
+		return
+	}
+	if err := h.userRepo.SetRole(userID, req.Role); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Set user role error: %v", err)
+		http.Error(w, "Failed to set role", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id": userID,
+		"role":    req.Role,
+	})
+}
+
+func (h *ModerationHandler) GetDuplicateCandidates(w http.ResponseWriter, r *http.Request) {
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	limit := 20
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 50 {
+		limit = l
+	}
+	candidates, err := h.duplicateRepo.FindCandidates(bookID, limit)
+	if err != nil {
+		if err.Error() == "book not found" {
+			http.Error(w, "Book not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Find duplicate candidates error: %v", err)
+		http.Error(w, "Failed to find duplicates", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(candidates)
+}
+
+func (h *ModerationHandler) ListDuplicateGroups(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+	groups, err := h.duplicateRepo.FindGroups(limit)
+	if err != nil {
+		log.Printf("Find duplicate groups error: %v", err)
+		http.Error(w, "Failed to find duplicates", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+func (h *ModerationHandler) MergeBooks(w http.ResponseWriter, r *http.Request) {
+	var req models.MergeBooksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.WinnerID <= 0 || req.LoserID <= 0 || req.WinnerID == req.LoserID {
+		http.Error(w, "winner_id and loser_id must be two different books", http.StatusBadRequest)
+		return
+	}
+	userIDs, err := h.duplicateRepo.Merge(req.WinnerID, req.LoserID)
+	if err != nil {
+		if err.Error() == "book not found" {
+			http.Error(w, "Book not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Merge books error: %v", err)
+		http.Error(w, "Failed to merge books", http.StatusInternalServerError)
+		return
+	}
+
+	cache.InvalidateBookCache(strconv.Itoa(req.WinnerID))
+	cache.InvalidateBookCache(strconv.Itoa(req.LoserID))
+	for _, userID := range userIDs {
+		cache.InvalidateUserCache(strconv.Itoa(userID))
+	}
+
+	book, err := h.bookRepo.GetByIDWithGenres(req.WinnerID)
+	if err != nil {
+		log.Printf("Get merged book error: %v", err)
+		http.Error(w, "Books merged but failed to load result", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(book)
+}
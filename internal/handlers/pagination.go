@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+	"net/http"
+	"strconv"
+)
+
+const cursorPageSize = 20
+
+// parsePage reads limit, offset and cursor from the query string. When no
+// limit is given defaultLimit applies; 0 keeps an endpoint returning every row
+// for clients that predate pagination. A cursor always gets a page size.
+func parsePage(r *http.Request, defaultLimit, maxLimit int) (models.PageParams, error) {
+	q := r.URL.Query()
+	page := models.PageParams{Limit: defaultLimit}
+
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 {
+		page.Limit = l
+	}
+	if maxLimit > 0 && page.Limit > maxLimit {
+		page.Limit = maxLimit
+	}
+	if o, err := strconv.Atoi(q.Get("offset")); err == nil && o >= 0 {
+		page.Offset = o
+	}
+
+	if token := q.Get("cursor"); token != "" {
+		after, err := models.DecodeCursor(token)
+		if err != nil {
+			return page, err
+		}
+		page.After = after
+	}
+	if usesCursor(r) && page.Limit == 0 {
+		page.Limit = cursorPageSize
+	}
+	return page, nil
+}
+
+// usesCursor reports whether the client asked for cursor pagination. The first
+// page is requested with an empty cursor= parameter.
+func usesCursor(r *http.Request) bool {
+	return r.URL.Query().Has("cursor")
+}
+
+// writePage encodes a plain array for offset clients and wraps the items with
+// next_cursor for cursor clients.
+func writePage(w http.ResponseWriter, r *http.Request, items interface{}, next string) {
+	w.Header().Set("Content-Type", "application/json")
+	if !usesCursor(r) {
+		json.NewEncoder(w).Encode(items)
+		return
+	}
+	json.NewEncoder(w).Encode(models.Page{Items: items, NextCursor: next})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/pulkyeet/BookmarkD/internal/cache"
+	"github.com/pulkyeet/BookmarkD/internal/database"
+	"github.com/pulkyeet/BookmarkD/internal/middleware"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+type ProgressHandler struct {
+	progressRepo *database.ProgressRepository
+}
+
+func NewProgressHandler(progressRepo *database.ProgressRepository) *ProgressHandler {
+	return &ProgressHandler{progressRepo: progressRepo}
+}
+
+// ListForBook returns the authenticated user's progress history for a book.
+func (h *ProgressHandler) ListForBook(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	updates, err := h.progressRepo.ListForBook(claims.UserID, bookID)
+	if err != nil {
+		log.Printf("List reading progress error: %v", err)
+		http.Error(w, "Failed to get progress", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updates)
+}
+
+// Create records how far the user has got with a book, by page or percent.
+func (h *ProgressHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	var req models.ProgressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	progress, err := h.progressRepo.Add(claims.UserID, bookID, req.Page, req.Percent, req.Comment)
+	if err == models.ErrInvalidProgress || err == models.ErrPagePastEnd {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil && err.Error() == "book not found" {
+		http.Error(w, "Book not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Add reading progress error: %v", err)
+		http.Error(w, "Failed to save progress", http.StatusInternalServerError)
+		return
+	}
+	cache.InvalidateUserCache(strconv.Itoa(claims.UserID))
+	cache.InvalidateBookCache(strconv.Itoa(bookID))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(progress)
+}
+
+func (h *ProgressHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	progressID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid progress ID", http.StatusBadRequest)
+		return
+	}
+	bookID, err := h.progressRepo.Delete(progressID, claims.UserID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Progress update not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Delete reading progress error: %v", err)
+		http.Error(w, "Failed to delete progress", http.StatusInternalServerError)
+		return
+	}
+	cache.InvalidateUserCache(strconv.Itoa(claims.UserID))
+	cache.InvalidateBookCache(strconv.Itoa(bookID))
+	w.WriteHeader(http.StatusNoContent)
+}
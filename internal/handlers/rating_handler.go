@@ -3,8 +3,10 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"github.com/pulkyeet/BookmarkD/internal/database"
 	"github.com/pulkyeet/BookmarkD/internal/middleware"
+	"github.com/pulkyeet/BookmarkD/internal/models"
 	"log"
 	"net/http"
 	"strconv"
@@ -39,9 +41,17 @@ func (h *RatingHandler) CreateRating(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Rating int    `json:"rating"`
-		Review string `json:"review"`
-		Status string `json:"status"`
+		Rating        int    `json:"rating"`
+		Review        string `json:"review"`
+		Spoiler       bool    `json:"spoiler"`
+		Language      string  `json:"language"`
+		Status        string  `json:"status"`
+		Visibility    string  `json:"visibility"`
+		PrivateNotes  *string `json:"private_notes"`
+		StartedAt     string  `json:"started_at"`
+		FinishedAt    string  `json:"finished_at"`
+		StoppedAt     string  `json:"stopped_at"`
+		StoppedAtPage *int    `json:"stopped_at_page"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -59,11 +69,21 @@ func (h *RatingHandler) CreateRating(w http.ResponseWriter, r *http.Request) {
 		"to_read":           true,
 		"currently_reading": true,
 		"finished_reading":  true,
+		"did_not_finish":    true,
 	}
 	if !validStatuses[req.Status] {
 		http.Error(w, "Invalid status", http.StatusBadRequest)
 		return
 	}
+	if req.Visibility != "" && !models.ValidVisibility(req.Visibility) {
+		http.Error(w, "Invalid visibility", http.StatusBadRequest)
+		return
+	}
+	language, err := models.NormalizeLanguage(req.Language)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Rating validation: allow 0 for to_read/currently_reading, require 1-10 for finished
 	if req.Status == "finished_reading" {
@@ -72,7 +92,7 @@ func (h *RatingHandler) CreateRating(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	} else {
-		// For to_read and currently_reading, use 0 if not provided
+		// For to_read, currently_reading and did_not_finish, use 0 if not provided
 		if req.Rating < 0 || req.Rating > 10 {
 			http.Error(w, "Invalid rating", http.StatusBadRequest)
 			return
@@ -83,7 +103,22 @@ func (h *RatingHandler) CreateRating(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	rating, err := h.ratingRepo.Upsert(userID, bookID, req.Rating, req.Review, req.Status)
+	dates, err := parseReadDates(req.StartedAt, req.FinishedAt, req.StoppedAt, req.StoppedAtPage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Status == "did_not_finish" && dates.FinishedAt != nil {
+		http.Error(w, "Use stopped_at for books you did not finish", http.StatusBadRequest)
+		return
+	}
+	if req.Status != "did_not_finish" && (dates.StoppedAt != nil || dates.StoppedAtPage != nil) {
+		http.Error(w, "stopped_at is only for books you did not finish", http.StatusBadRequest)
+		return
+	}
+
+	privacy := models.EntryPrivacy{Visibility: req.Visibility, PrivateNotes: req.PrivateNotes}
+	rating, err := h.ratingRepo.Upsert(userID, bookID, req.Rating, req.Review, req.Spoiler, language, req.Status, privacy, dates)
 	if err != nil {
 		log.Printf("Upsert error: %v", err)
 		http.Error(w, "Failed to create rating", http.StatusInternalServerError)
@@ -91,6 +126,9 @@ func (h *RatingHandler) CreateRating(w http.ResponseWriter, r *http.Request) {
 	}
 	cache.InvalidateUserCache(strconv.Itoa(userID))
 	cache.InvalidateBookCache(strconv.Itoa(bookID))
+	if req.Visibility != "" {
+		cache.InvalidateFeedCache()
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(rating)
 }
@@ -118,7 +156,23 @@ func (h *RatingHandler) GetBookRatings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats, err := h.ratingRepo.GetByBookID(bookID, userID, sortBy)
+	filter, err := parseReviewFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Following && userID == nil {
+		http.Error(w, "Log in to see reviews from people you follow", http.StatusUnauthorized)
+		return
+	}
+
+	page, err := parsePage(r, 20, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.ratingRepo.GetByBookID(bookID, userID, sortBy, filter, page)
 	if err != nil {
 		log.Printf("Error getting ratings for book %d: %v", bookID, err)
 		http.Error(w, "Failed to get ratings", http.StatusInternalServerError)
@@ -128,6 +182,58 @@ func (h *RatingHandler) GetBookRatings(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(stats)
 }
 
+// GetHistogram returns a book's rating distribution and status breakdown.
+func (h *RatingHandler) GetHistogram(w http.ResponseWriter, r *http.Request) {
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book id", http.StatusBadRequest)
+		return
+	}
+	histogram, err := h.ratingRepo.GetHistogram(bookID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Book not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting rating histogram for book %d: %v", bookID, err)
+		http.Error(w, "Failed to get rating histogram", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(histogram)
+}
+
+// parseReviewFilter reads the has_review, min_rating, max_rating, following
+// and language query parameters.
+func parseReviewFilter(r *http.Request) (models.ReviewFilter, error) {
+	q := r.URL.Query()
+	filter := models.ReviewFilter{
+		HasReview: q.Get("has_review") == "true",
+		Following: q.Get("following") == "true",
+	}
+	for _, bound := range []struct {
+		name string
+		dest *int
+	}{{"min_rating", &filter.MinRating}, {"max_rating", &filter.MaxRating}} {
+		if v := q.Get(bound.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 10 {
+				return filter, fmt.Errorf("%s must be between 1 and 10", bound.name)
+			}
+			*bound.dest = n
+		}
+	}
+	if filter.MinRating > 0 && filter.MaxRating > 0 && filter.MinRating > filter.MaxRating {
+		return filter, fmt.Errorf("min_rating can't be above max_rating")
+	}
+	language, err := models.NormalizeLanguage(q.Get("language"))
+	if err != nil {
+		return filter, err
+	}
+	filter.Language = language
+	return filter, nil
+}
+
 func (h *RatingHandler) GetMyRatings(w http.ResponseWriter, r *http.Request) {
 	claims, ok := middleware.GetUserFromContext(r)
 	if !ok {
@@ -137,14 +243,18 @@ func (h *RatingHandler) GetMyRatings(w http.ResponseWriter, r *http.Request) {
 	userID := claims.UserID
 
 	status := r.URL.Query().Get("status")
+	page, err := parsePage(r, 0, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
 
-	ratings, err := h.ratingRepo.GetByUserIDWithStatus(userID, status)
+	ratings, next, err := h.ratingRepo.GetByUserIDWithStatus(userID, status, page)
 	if err != nil {
 		http.Error(w, "Failed to get ratings", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(ratings)
+	writePage(w, r, ratings, next)
 }
 
 func (h *RatingHandler) DeleteRating(w http.ResponseWriter, r *http.Request) {
@@ -247,6 +357,35 @@ func (h *RatingHandler) UnlikeRating(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// GetRevisions lists the earlier versions of a review.
+func (h *RatingHandler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	ratingID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid rating ID", http.StatusBadRequest)
+		return
+	}
+	page, err := parsePage(r, 0, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	var viewerID *int
+	if claims, ok := middleware.GetUserFromContext(r); ok {
+		viewerID = &claims.UserID
+	}
+	revisions, next, err := h.ratingRepo.GetRevisions(ratingID, viewerID, page)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Rating not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting revisions for rating %d: %v", ratingID, err)
+		http.Error(w, "Failed to get revisions", http.StatusInternalServerError)
+		return
+	}
+	writePage(w, r, revisions, next)
+}
+
 func (h *RatingHandler) UpdateRating(w http.ResponseWriter, r *http.Request) {
 	claims, ok := middleware.GetUserFromContext(r)
 	if !ok {
@@ -259,18 +398,41 @@ func (h *RatingHandler) UpdateRating(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		Rating int    `json:"rating"`
-		Review string `json:"review"`
+		Rating        int    `json:"rating"`
+		Review        string `json:"review"`
+		Spoiler       bool    `json:"spoiler"`
+		Language      string  `json:"language"`
+		Visibility    string  `json:"visibility"`
+		PrivateNotes  *string `json:"private_notes"`
+		StartedAt     string  `json:"started_at"`
+		FinishedAt    string  `json:"finished_at"`
+		StoppedAt     string  `json:"stopped_at"`
+		StoppedAtPage *int    `json:"stopped_at_page"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid Request Body", http.StatusBadRequest)
 		return
 	}
+	if req.Visibility != "" && !models.ValidVisibility(req.Visibility) {
+		http.Error(w, "Invalid visibility", http.StatusBadRequest)
+		return
+	}
+	language, err := models.NormalizeLanguage(req.Language)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if req.Rating > 10 && req.Rating < 0 {
 		http.Error(w, "Rating must be between 1 and 10", http.StatusBadRequest)
 		return
 	}
-	rating, err := h.ratingRepo.Update(ratingID, claims.UserID, req.Rating, req.Review)
+	dates, err := parseReadDates(req.StartedAt, req.FinishedAt, req.StoppedAt, req.StoppedAtPage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	privacy := models.EntryPrivacy{Visibility: req.Visibility, PrivateNotes: req.PrivateNotes}
+	rating, err := h.ratingRepo.Update(ratingID, claims.UserID, req.Rating, req.Review, req.Spoiler, language, privacy, dates)
 	if err == sql.ErrNoRows {
 		http.Error(w, "Rating not found", http.StatusInternalServerError)
 		return
@@ -281,6 +443,9 @@ func (h *RatingHandler) UpdateRating(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	cache.InvalidateUserCache(strconv.Itoa(claims.UserID))
+	if req.Visibility != "" {
+		cache.InvalidateFeedCache()
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(rating)
 }
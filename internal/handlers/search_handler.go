@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pulkyeet/BookmarkD/internal/database"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+// suggestTimeout keeps typeahead responsive; a slow lookup returns nothing rather than lagging the input.
+const suggestTimeout = 300 * time.Millisecond
+
+type SearchHandler struct {
+	searchRepo *database.SearchRepository
+}
+
+func NewSearchHandler(searchRepo *database.SearchRepository) *SearchHandler {
+	return &SearchHandler{searchRepo: searchRepo}
+}
+
+func (h *SearchHandler) Suggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len([]rune(q)) < 2 {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Suggestion{})
+		return
+	}
+	limit := 10
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 20 {
+			limit = l
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), suggestTimeout)
+	defer cancel()
+
+	suggestions, err := h.searchRepo.Suggest(ctx, q, limit)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Printf("Suggest timed out for %q", q)
+			http.Error(w, "Suggestions timed out", http.StatusServiceUnavailable)
+			return
+		}
+		log.Printf("Suggest error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/pulkyeet/BookmarkD/internal/cache"
+	"github.com/pulkyeet/BookmarkD/internal/database"
+	"github.com/pulkyeet/BookmarkD/internal/middleware"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+type SeriesHandler struct {
+	seriesRepo *database.SeriesRepository
+}
+
+func NewSeriesHandler(seriesRepo *database.SeriesRepository) *SeriesHandler {
+	return &SeriesHandler{seriesRepo: seriesRepo}
+}
+
+func (h *SeriesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		http.Error(w, "Series name is required", http.StatusBadRequest)
+		return
+	}
+	series, err := h.seriesRepo.Create(req.Name, req.Description)
+	if err != nil {
+		log.Printf("Create series error: %v", err)
+		http.Error(w, "Failed to create series", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(series)
+}
+
+func (h *SeriesHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	seriesID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid series ID", http.StatusBadRequest)
+		return
+	}
+	var viewerID *int
+	if claims, ok := middleware.GetUserFromContext(r); ok {
+		viewerID = &claims.UserID
+	}
+	series, err := h.seriesRepo.GetByID(seriesID, viewerID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Series not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Get series error: %v", err)
+		http.Error(w, "Failed to get series", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
+func (h *SeriesHandler) AddBook(w http.ResponseWriter, r *http.Request) {
+	seriesID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid series ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		BookID   int     `json:"book_id"`
+		Position float64 `json:"position"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !models.ValidSeriesPosition(req.Position) {
+		http.Error(w, "Position must be between 0 and 9999.99 with at most two decimal places", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.seriesRepo.GetByID(seriesID, nil); err == sql.ErrNoRows {
+		http.Error(w, "Series not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Get series error: %v", err)
+		http.Error(w, "Failed to add book", http.StatusInternalServerError)
+		return
+	}
+	err = h.seriesRepo.AddBook(seriesID, req.BookID, req.Position)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Book not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Add book to series error: %v", err)
+		http.Error(w, "Failed to add book", http.StatusInternalServerError)
+		return
+	}
+	cache.InvalidateBookCacheByID(req.BookID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SeriesHandler) RemoveBook(w http.ResponseWriter, r *http.Request) {
+	seriesID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid series ID", http.StatusBadRequest)
+		return
+	}
+	bookID, err := strconv.Atoi(r.PathValue("bookID"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	err = h.seriesRepo.RemoveBook(seriesID, bookID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Book not found in series", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Remove book from series error: %v", err)
+		http.Error(w, "Failed to remove book", http.StatusInternalServerError)
+		return
+	}
+	cache.InvalidateBookCacheByID(bookID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pulkyeet/BookmarkD/internal/cache"
+	"github.com/pulkyeet/BookmarkD/internal/database"
+	"github.com/pulkyeet/BookmarkD/internal/middleware"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+type ReadingSessionHandler struct {
+	sessionRepo *database.ReadingSessionRepository
+}
+
+func NewReadingSessionHandler(sessionRepo *database.ReadingSessionRepository) *ReadingSessionHandler {
+	return &ReadingSessionHandler{sessionRepo: sessionRepo}
+}
+
+// ListForBook returns the authenticated user's reads of a book.
+func (h *ReadingSessionHandler) ListForBook(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	sessions, err := h.sessionRepo.ListForBook(claims.UserID, bookID)
+	if err != nil {
+		log.Printf("List reading sessions error: %v", err)
+		http.Error(w, "Failed to get reads", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// Create logs a past read or starts a new one.
+func (h *ReadingSessionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	var req models.ReadingSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	dates, err := parseSessionRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.sessionRepo.Create(claims.UserID, bookID, dates, req.Rating, req.Notes)
+	if err == models.ErrReadInProgress {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil && err.Error() == "book not found" {
+		http.Error(w, "Book not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Create reading session error: %v", err)
+		http.Error(w, "Failed to log read", http.StatusInternalServerError)
+		return
+	}
+	cache.InvalidateUserCache(strconv.Itoa(claims.UserID))
+	cache.InvalidateBookCache(strconv.Itoa(bookID))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+func (h *ReadingSessionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	sessionID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+	var req models.ReadingSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	dates, err := parseSessionRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.sessionRepo.Update(sessionID, claims.UserID, dates, req.Rating, req.Notes)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Read not found", http.StatusNotFound)
+		return
+	}
+	if err == models.ErrReadInProgress {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Update reading session error: %v", err)
+		http.Error(w, "Failed to update read", http.StatusInternalServerError)
+		return
+	}
+	cache.InvalidateUserCache(strconv.Itoa(claims.UserID))
+	cache.InvalidateBookCache(strconv.Itoa(session.BookID))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+func (h *ReadingSessionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	sessionID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+	bookID, err := h.sessionRepo.Delete(sessionID, claims.UserID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Read not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Delete reading session error: %v", err)
+		http.Error(w, "Failed to delete read", http.StatusInternalServerError)
+		return
+	}
+	cache.InvalidateUserCache(strconv.Itoa(claims.UserID))
+	cache.InvalidateBookCache(strconv.Itoa(bookID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseSessionRequest(req models.ReadingSessionRequest) (models.ReadDates, error) {
+	if req.Rating < 0 || req.Rating > 10 {
+		return models.ReadDates{}, fmt.Errorf("Rating must be between 0 and 10")
+	}
+	dates, err := parseReadDates(req.StartedAt, req.FinishedAt, req.StoppedAt, req.StoppedAtPage)
+	if err != nil {
+		return dates, err
+	}
+	if dates.StoppedAtPage != nil && dates.StoppedAt == nil {
+		return dates, fmt.Errorf("stopped_at_page needs a stopped_at date")
+	}
+	if req.Finished && dates.StoppedAt != nil {
+		return dates, fmt.Errorf("a read can't be both finished and stopped")
+	}
+	dates.FinishedUndated = req.Finished && dates.FinishedAt == nil
+	return dates, nil
+}
+
+// parseReadDates validates YYYY-MM-DD start, finish and stop dates, any of
+// which may be empty, and the page a read was given up at.
+func parseReadDates(startedRaw, finishedRaw, stoppedRaw string, stoppedAtPage *int) (models.ReadDates, error) {
+	dates := models.ReadDates{StoppedAtPage: stoppedAtPage}
+	var err error
+	if dates.StartedAt, err = models.ParseReadDate(startedRaw); err != nil {
+		return dates, err
+	}
+	if dates.FinishedAt, err = models.ParseReadDate(finishedRaw); err != nil {
+		return dates, err
+	}
+	if dates.StoppedAt, err = models.ParseReadDate(stoppedRaw); err != nil {
+		return dates, err
+	}
+	if dates.FinishedAt != nil && dates.StoppedAt != nil {
+		return dates, fmt.Errorf("a read can't be both finished and stopped")
+	}
+	if dates.StartedAt != nil && dates.FinishedAt != nil && dates.FinishedAt.Before(*dates.StartedAt) {
+		return dates, fmt.Errorf("finished_at cannot be before started_at")
+	}
+	if dates.StartedAt != nil && dates.StoppedAt != nil && dates.StoppedAt.Before(*dates.StartedAt) {
+		return dates, fmt.Errorf("stopped_at cannot be before started_at")
+	}
+	// Allow a day of slack for readers ahead of UTC
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	if dates.FinishedAt != nil && dates.FinishedAt.After(tomorrow) {
+		return dates, fmt.Errorf("finished_at cannot be in the future")
+	}
+	if dates.StoppedAt != nil && dates.StoppedAt.After(tomorrow) {
+		return dates, fmt.Errorf("stopped_at cannot be in the future")
+	}
+	if stoppedAtPage != nil && *stoppedAtPage < 0 {
+		return dates, fmt.Errorf("stopped_at_page cannot be negative")
+	}
+	return dates, nil
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/pulkyeet/BookmarkD/internal/database"
+	"github.com/pulkyeet/BookmarkD/internal/middleware"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+type ShelfHandler struct {
+	shelfRepo *database.ShelfRepository
+}
+
+func NewShelfHandler(shelfRepo *database.ShelfRepository) *ShelfHandler {
+	return &ShelfHandler{shelfRepo: shelfRepo}
+}
+
+type shelfRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (h *ShelfHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req shelfRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	name, err := models.NormalizeShelfName(req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	shelf, err := h.shelfRepo.Create(claims.UserID, name, req.Description)
+	if err == models.ErrShelfExists {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error creating shelf: %v", err)
+		http.Error(w, "Failed to create shelf", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shelf)
+}
+
+func (h *ShelfHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	shelfID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid shelf ID", http.StatusBadRequest)
+		return
+	}
+	var viewerID *int
+	if claims, ok := middleware.GetUserFromContext(r); ok {
+		viewerID = &claims.UserID
+	}
+	shelf, err := h.shelfRepo.GetByID(shelfID, viewerID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Shelf not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting shelf: %v", err)
+		http.Error(w, "Failed to get shelf", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shelf)
+}
+
+// GetUserShelves lists a user's custom shelves for their profile.
+func (h *ShelfHandler) GetUserShelves(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	var viewerID *int
+	if claims, ok := middleware.GetUserFromContext(r); ok {
+		viewerID = &claims.UserID
+	}
+	shelves, err := h.shelfRepo.GetByUserID(userID, viewerID)
+	if err != nil {
+		log.Printf("Error getting shelves for user %d: %v", userID, err)
+		http.Error(w, "Failed to get shelves", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shelves)
+}
+
+func (h *ShelfHandler) Update(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	shelfID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid shelf ID", http.StatusBadRequest)
+		return
+	}
+	var req shelfRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	name, err := models.NormalizeShelfName(req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	shelf, err := h.shelfRepo.Update(shelfID, claims.UserID, name, req.Description)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Shelf not found", http.StatusNotFound)
+		return
+	}
+	if err == models.ErrShelfExists {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating shelf: %v", err)
+		http.Error(w, "Failed to update shelf", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shelf)
+}
+
+func (h *ShelfHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	shelfID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid shelf ID", http.StatusBadRequest)
+		return
+	}
+	err = h.shelfRepo.Delete(shelfID, claims.UserID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Shelf not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting shelf: %v", err)
+		http.Error(w, "Failed to delete shelf", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ShelfHandler) GetBooks(w http.ResponseWriter, r *http.Request) {
+	shelfID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid shelf ID", http.StatusBadRequest)
+		return
+	}
+	page, err := parsePage(r, 0, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	var viewerID *int
+	if claims, ok := middleware.GetUserFromContext(r); ok {
+		viewerID = &claims.UserID
+	}
+	if _, err := h.shelfRepo.GetByID(shelfID, viewerID); err == sql.ErrNoRows {
+		http.Error(w, "Shelf not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error getting shelf: %v", err)
+		http.Error(w, "Failed to get shelf books", http.StatusInternalServerError)
+		return
+	}
+	books, next, err := h.shelfRepo.GetBooks(shelfID, viewerID, page)
+	if err != nil {
+		log.Printf("Error getting shelf books: %v", err)
+		http.Error(w, "Failed to get shelf books", http.StatusInternalServerError)
+		return
+	}
+	writePage(w, r, books, next)
+}
+
+func (h *ShelfHandler) AddBook(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	shelfID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid shelf ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		BookID int `json:"book_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !h.authorizeShelf(w, shelfID, claims.UserID) {
+		return
+	}
+	err = h.shelfRepo.AddBook(shelfID, req.BookID)
+	if err != nil && err.Error() == "book not found" {
+		http.Error(w, "Book not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error adding book to shelf: %v", err)
+		http.Error(w, "Failed to add book", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ShelfHandler) RemoveBook(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	shelfID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid shelf ID", http.StatusBadRequest)
+		return
+	}
+	bookID, err := strconv.Atoi(r.PathValue("bookID"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	if !h.authorizeShelf(w, shelfID, claims.UserID) {
+		return
+	}
+	err = h.shelfRepo.RemoveBook(shelfID, bookID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Book not on shelf", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error removing book from shelf: %v", err)
+		http.Error(w, "Failed to remove book", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizeShelf checks the shelf exists and belongs to the user, writing the
+// error response if not.
+func (h *ShelfHandler) authorizeShelf(w http.ResponseWriter, shelfID, userID int) bool {
+	shelf, err := h.shelfRepo.GetByID(shelfID, &userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Shelf not found", http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		log.Printf("Error getting shelf: %v", err)
+		http.Error(w, "Failed to get shelf", http.StatusInternalServerError)
+		return false
+	}
+	if shelf.UserID != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/pulkyeet/BookmarkD/internal/cache"
+	"github.com/pulkyeet/BookmarkD/internal/database"
+	"github.com/pulkyeet/BookmarkD/internal/middleware"
+	"github.com/pulkyeet/BookmarkD/internal/models"
+)
+
+type TagHandler struct {
+	tagRepo *database.TagRepository
+}
+
+func NewTagHandler(tagRepo *database.TagRepository) *TagHandler {
+	return &TagHandler{tagRepo: tagRepo}
+}
+
+func (h *TagHandler) GetPopular(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	tags, err := h.tagRepo.Popular(r.URL.Query().Get("q"), limit)
+	if err != nil {
+		log.Printf("Get popular tags error: %v", err)
+		http.Error(w, "Failed to get tags", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+func (h *TagHandler) GetBookTags(w http.ResponseWriter, r *http.Request) {
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	var viewerID *int
+	if claims, ok := middleware.GetUserFromContext(r); ok {
+		viewerID = &claims.UserID
+	}
+	h.writeBookTags(w, bookID, viewerID)
+}
+
+func (h *TagHandler) Vote(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	name, err := models.NormalizeTagName(req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.tagRepo.Vote(bookID, claims.UserID, name); err != nil {
+		if errors.Is(err, models.ErrTagLimit) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		if err.Error() == "book not found" {
+			http.Error(w, "Book not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Tag vote error: %v", err)
+		http.Error(w, "Failed to tag book", http.StatusInternalServerError)
+		return
+	}
+	cache.InvalidateBookCache(strconv.Itoa(bookID))
+	h.writeBookTags(w, bookID, &claims.UserID)
+}
+
+func (h *TagHandler) Unvote(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	bookID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	name, err := models.NormalizeTagName(r.PathValue("name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.tagRepo.Unvote(bookID, claims.UserID, name); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "You haven't applied this tag", http.StatusNotFound)
+			return
+		}
+		if err.Error() == "book not found" {
+			http.Error(w, "Book not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Tag unvote error: %v", err)
+		http.Error(w, "Failed to remove tag", http.StatusInternalServerError)
+		return
+	}
+	cache.InvalidateBookCache(strconv.Itoa(bookID))
+	h.writeBookTags(w, bookID, &claims.UserID)
+}
+
+func (h *TagHandler) writeBookTags(w http.ResponseWriter, bookID int, viewerID *int) {
+	tags, err := h.tagRepo.GetForBook(bookID, viewerID)
+	if err != nil {
+		if err.Error() == "book not found" {
+			http.Error(w, "Book not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Get book tags error: %v", err)
+		http.Error(w, "Failed to get tags", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
@@ -128,13 +128,17 @@ func (h *UserHandler) GetFollowers(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
-	followers, err := h.followRepo.GetFollowers(userID)
+	page, err := parsePage(r, 0, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	followers, next, err := h.followRepo.GetFollowers(userID, page)
 	if err != nil {
 		http.Error(w, "Failed to get followers", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(followers)
+	writePage(w, r, followers, next)
 }
 
 func (h *UserHandler) GetFollowing(w http.ResponseWriter, r *http.Request) {
@@ -148,13 +152,67 @@ func (h *UserHandler) GetFollowing(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
-	following, err := h.followRepo.GetFollowing(userID)
+	page, err := parsePage(r, 0, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	following, next, err := h.followRepo.GetFollowing(userID, page)
 	if err != nil {
 		http.Error(w, "Failed to get followers", http.StatusInternalServerError)
 		return
 	}
+	writePage(w, r, following, next)
+}
+
+func (h *UserHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	prefs, err := h.userRepo.GetPreferences(claims.UserID)
+	if err != nil {
+		log.Printf("Error getting preferences for user %d: %v", claims.UserID, err)
+		http.Error(w, "Failed to get preferences", http.StatusInternalServerError)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(following)
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// UpdatePreferences changes only the preferences present in the request body.
+func (h *UserHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		RevealFinishedSpoilers *bool `json:"reveal_finished_spoilers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	prefs, err := h.userRepo.GetPreferences(claims.UserID)
+	if err != nil {
+		log.Printf("Error getting preferences for user %d: %v", claims.UserID, err)
+		http.Error(w, "Failed to update preferences", http.StatusInternalServerError)
+		return
+	}
+	if req.RevealFinishedSpoilers != nil {
+		prefs.RevealFinishedSpoilers = *req.RevealFinishedSpoilers
+	}
+	if err := h.userRepo.UpdatePreferences(claims.UserID, prefs); err != nil {
+		log.Printf("Error updating preferences for user %d: %v", claims.UserID, err)
+		http.Error(w, "Failed to update preferences", http.StatusInternalServerError)
+		return
+	}
+	// Cached feeds carry the old spoiler visibility
+	cache.InvalidateUserCache(strconv.Itoa(claims.UserID))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
 }
 
 type UserHandlerWithStats struct {
@@ -185,7 +243,11 @@ func (h *UserHandlerWithStats) GetYearStats(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "Invalid year", http.StatusBadRequest)
 		return
 	}
-	stats, err := h.ratingRepo.GetYearStats(userID, year)
+	var viewerID *int
+	if claims, ok := middleware.GetUserFromContext(r); ok {
+		viewerID = &claims.UserID
+	}
+	stats, err := h.ratingRepo.GetYearStats(userID, year, viewerID)
 	if err != nil {
 		log.Printf("GetYearStats error: %v", err)
 		http.Error(w, "Failed to get stats", http.StatusInternalServerError)
@@ -0,0 +1,332 @@
+// Package markdown renders the Markdown subset allowed in reviews and comments:
+// paragraphs, block quotes, lists, emphasis, links and [spoiler] markup.
+//
+// The output is built only from escaped text and the tags generated here, so
+// it is safe to insert into a page as HTML. Raw HTML in the source is escaped,
+// never passed through.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	spoilerOpen  = "[spoiler]"
+	spoilerClose = "[/spoiler]"
+	// SpoilerPlaceholder stands in for spoilers in snippets
+	SpoilerPlaceholder = "[spoiler]"
+	// maxQuoteDepth caps how deeply block quotes nest
+	maxQuoteDepth = 5
+	// Link labels and URLs longer than these are left as text
+	maxLinkLabel = 500
+	maxLinkURL   = 2048
+)
+
+var (
+	unorderedItem = regexp.MustCompile(`^ {0,3}[-*+][ \t]+(.*)$`)
+	orderedItem   = regexp.MustCompile(`^ {0,3}(\d{1,9})[.)][ \t]+(.*)$`)
+	quoteLine     = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+)
+
+// Render converts source to sanitized HTML. Spoilers are wrapped in
+// <span class="spoiler"> for clients to hide.
+func Render(source string) string {
+	r := &renderer{}
+	return r.blocks(splitLines(source), 0)
+}
+
+// RenderSnippet renders source for previews shown off the site, such as
+// embeds. Readers there can't reveal spoilers, so each is replaced with
+// SpoilerPlaceholder, and links are reduced to their text because the preview
+// usually sits inside a link of its own.
+func RenderSnippet(source string) string {
+	r := &renderer{snippet: true}
+	return r.blocks(splitLines(source), 0)
+}
+
+type renderer struct {
+	snippet bool
+	// inSpoiler carries an open [spoiler] tag from one block to the next
+	inSpoiler bool
+}
+
+func splitLines(source string) []string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+	return strings.Split(source, "\n")
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// blocks renders lines as a sequence of paragraphs, quotes and lists.
+func (r *renderer) blocks(lines []string, depth int) string {
+	var out []string
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+
+		case quoteLine.MatchString(line) && depth < maxQuoteDepth:
+			var quoted []string
+			for ; i < len(lines) && quoteLine.MatchString(lines[i]); i++ {
+				quoted = append(quoted, quoteLine.FindStringSubmatch(lines[i])[1])
+			}
+			out = append(out, "<blockquote>\n"+r.blocks(quoted, depth+1)+"\n</blockquote>")
+
+		case unorderedItem.MatchString(line):
+			var items []string
+			items, i = collectItems(lines, i, unorderedItem, 1)
+			out = append(out, r.list("ul", "", items))
+
+		case orderedItem.MatchString(line):
+			start := ""
+			if n, _ := strconv.Atoi(orderedItem.FindStringSubmatch(line)[1]); n != 1 {
+				start = strconv.Itoa(n)
+			}
+			var items []string
+			items, i = collectItems(lines, i, orderedItem, 2)
+			out = append(out, r.list("ol", start, items))
+
+		default:
+			var para []string
+			for ; i < len(lines) && !isBlank(lines[i]) && !startsBlock(lines[i]); i++ {
+				para = append(para, strings.TrimSpace(lines[i]))
+			}
+			out = append(out, "<p>"+r.inlineLines(para)+"</p>")
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func startsBlock(line string) bool {
+	return quoteLine.MatchString(line) || unorderedItem.MatchString(line) || orderedItem.MatchString(line)
+}
+
+// collectItems gathers consecutive list items starting at lines[i]. An
+// indented line under an item continues it.
+func collectItems(lines []string, i int, item *regexp.Regexp, textGroup int) ([]string, int) {
+	var items []string
+	for i < len(lines) {
+		if m := item.FindStringSubmatch(lines[i]); m != nil {
+			items = append(items, m[textGroup])
+		} else if len(items) > 0 && !isBlank(lines[i]) && (lines[i][0] == ' ' || lines[i][0] == '\t') && !startsBlock(lines[i]) {
+			items[len(items)-1] += "\n" + strings.TrimSpace(lines[i])
+		} else {
+			break
+		}
+		i++
+	}
+	return items, i
+}
+
+func (r *renderer) list(tag, start string, items []string) string {
+	var b strings.Builder
+	b.WriteString("<" + tag)
+	if start != "" {
+		b.WriteString(` start="` + start + `"`)
+	}
+	b.WriteString(">\n")
+	for _, item := range items {
+		b.WriteString("<li>" + r.inlineLines(strings.Split(item, "\n")) + "</li>\n")
+	}
+	b.WriteString("</" + tag + ">")
+	return b.String()
+}
+
+// inlineLines renders the lines of one block, keeping line breaks and
+// wrapping spoiler sections. A spoiler left open runs on into later blocks.
+func (r *renderer) inlineLines(lines []string) string {
+	text := strings.Join(lines, "\n")
+	var b strings.Builder
+	for text != "" {
+		section, rest, found := SplitSpoiler(text, r.inSpoiler)
+		b.WriteString(r.section(section))
+		text = rest
+		if found {
+			r.inSpoiler = !r.inSpoiler
+		}
+	}
+	return strings.ReplaceAll(b.String(), "\n", "<br>\n")
+}
+
+// SplitSpoiler finds the next spoiler tag in text: [spoiler] outside a
+// spoiler, [/spoiler] inside one, matched case-insensitively. It returns the
+// text before and after the tag, or all of text and false if there's none.
+// Everything that reads spoiler markup goes through here so that no two
+// readers can disagree about where a spoiler ends.
+func SplitSpoiler(text string, inSpoiler bool) (before, after string, found bool) {
+	tag := spoilerOpen
+	if inSpoiler {
+		tag = spoilerClose
+	}
+	end := indexFold(text, tag)
+	if end == -1 {
+		return text, "", false
+	}
+	return text[:end], text[end+len(tag):], true
+}
+
+func (r *renderer) section(text string) string {
+	if text == "" {
+		return ""
+	}
+	if !r.inSpoiler {
+		return inline(text, !r.snippet)
+	}
+	if r.snippet {
+		return `<span class="spoiler">` + html.EscapeString(SpoilerPlaceholder) + `</span>`
+	}
+	return `<span class="spoiler">` + inline(text, true) + `</span>`
+}
+
+// inline renders emphasis, links and backslash escapes within text, escaping
+// everything else. Delimiters that aren't closed are left as literal text.
+// Without links, a link is rendered as just its label.
+func inline(text string, links bool) string {
+	var b strings.Builder
+	// Once a delimiter has no closer left in text, later ones won't either, so
+	// unclosed delimiters don't each rescan the rest of the text
+	unclosed := map[string]bool{}
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isPunct(text[i+1]):
+			b.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+			continue
+
+		case (c == '*' || c == '_') && i+1 < len(text) && text[i+1] == c:
+			delim := text[i : i+2]
+			if !unclosed[delim] && canOpen(text, i, 2) {
+				if end := strings.Index(text[i+2:], delim); end > 0 {
+					b.WriteString("<strong>" + inline(text[i+2:i+2+end], links) + "</strong>")
+					i += 2 + end + 2
+					continue
+				}
+				unclosed[delim] = true
+			}
+			// Emit both characters so the second isn't read as an opener
+			b.WriteString(html.EscapeString(delim))
+			i += 2
+			continue
+
+		case c == '*' || c == '_':
+			delim := text[i : i+1]
+			if !unclosed[delim] && canOpen(text, i, 1) {
+				if end := singleCloser(text, i); end != -1 {
+					b.WriteString("<em>" + inline(text[i+1:end], links) + "</em>")
+					i = end + 1
+					continue
+				}
+				unclosed[delim] = true
+			}
+
+		case c == '[':
+			if label, href, n, ok := parseLink(text[i:]); ok {
+				if links {
+					b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow ugc noopener" target="_blank">` + inline(label, false) + "</a>")
+				} else {
+					b.WriteString(inline(label, false))
+				}
+				i += n
+				continue
+			}
+		}
+		b.WriteString(html.EscapeString(text[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// canOpen reports whether the n-character delimiter at i can open emphasis:
+// it must be followed by non-space, and underscores can't open mid-word.
+func canOpen(text string, i, n int) bool {
+	if i+n >= len(text) || text[i+n] == ' ' || text[i+n] == '\n' {
+		return false
+	}
+	if text[i] == '_' && i > 0 && isWordByte(text[i-1]) {
+		return false
+	}
+	return true
+}
+
+// singleCloser finds the single delimiter closing the one at i, skipping
+// doubled delimiters that belong to strong emphasis.
+func singleCloser(text string, i int) int {
+	c := text[i]
+	for j := i + 1; j < len(text); j++ {
+		if text[j] != c {
+			continue
+		}
+		if j+1 < len(text) && text[j+1] == c {
+			j++
+			continue
+		}
+		if j == i+1 || text[j-1] == ' ' || text[j-1] == '\n' {
+			continue
+		}
+		if c == '_' && j+1 < len(text) && isWordByte(text[j+1]) {
+			continue
+		}
+		return j
+	}
+	return -1
+}
+
+// parseLink parses [label](url) at the start of text, returning how many bytes
+// it used. Only http, https and mailto links are allowed.
+func parseLink(text string) (label, href string, n int, ok bool) {
+	closeLabel := strings.Index(text[:min(len(text), maxLinkLabel+2)], "](")
+	if closeLabel <= 1 || strings.Contains(text[1:closeLabel], "\n") {
+		return "", "", 0, false
+	}
+	rest := text[closeLabel+2:]
+	closeURL := strings.IndexByte(rest[:min(len(rest), maxLinkURL+1)], ')')
+	if closeURL <= 0 {
+		return "", "", 0, false
+	}
+	href = text[closeLabel+2 : closeLabel+2+closeURL]
+	if strings.IndexFunc(href, unicode.IsSpace) != -1 {
+		return "", "", 0, false
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", "", 0, false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return "", "", 0, false
+		}
+	case "mailto":
+	default:
+		return "", "", 0, false
+	}
+	return text[1:closeLabel], u.String(), closeLabel + 2 + closeURL + 1, true
+}
+
+func isPunct(c byte) bool {
+	return c < 0x80 && (unicode.IsPunct(rune(c)) || unicode.IsSymbol(rune(c)))
+}
+
+func isWordByte(c byte) bool {
+	return c >= 0x80 || c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// indexFold is strings.Index ignoring ASCII case in sub.
+func indexFold(s, sub string) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(sub)], sub) {
+			return i
+		}
+	}
+	return -1
+}
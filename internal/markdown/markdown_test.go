@@ -0,0 +1,190 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+const linkAttrs = `" rel="nofollow ugc noopener" target="_blank">`
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "paragraphs and line breaks",
+			source: "one\ntwo\n\nthree",
+			want:   "<p>one<br>\ntwo</p>\n<p>three</p>",
+		},
+		{
+			name:   "raw html is escaped",
+			source: `<script>alert("x")</script>`,
+			want:   "<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>",
+		},
+		{
+			name:   "html inside emphasis is escaped",
+			source: "**<img src=x onerror=alert(1)>**",
+			want:   "<p><strong>&lt;img src=x onerror=alert(1)&gt;</strong></p>",
+		},
+		{
+			name:   "http link",
+			source: "[site](https://example.com/a)",
+			want:   `<p><a href="https://example.com/a` + linkAttrs + `site</a></p>`,
+		},
+		{
+			name:   "mailto link",
+			source: "[mail](mailto:me@example.com)",
+			want:   `<p><a href="mailto:me@example.com` + linkAttrs + `mail</a></p>`,
+		},
+		{
+			name:   "javascript link is left as text",
+			source: "[x](javascript:alert(1))",
+			want:   "<p>[x](javascript:alert(1))</p>",
+		},
+		{
+			name:   "javascript link with mixed case",
+			source: "[x](JavaScript:alert(1))",
+			want:   "<p>[x](JavaScript:alert(1))</p>",
+		},
+		{
+			name:   "data link is left as text",
+			source: "[x](data:text/html;base64,PHNjcmlwdD4=)",
+			want:   "<p>[x](data:text/html;base64,PHNjcmlwdD4=)</p>",
+		},
+		{
+			name:   "scheme-relative link is left as text",
+			source: "[x](//evil.example)",
+			want:   "<p>[x](//evil.example)</p>",
+		},
+		{
+			name:   "quote in href can't break out of the attribute",
+			source: `[x](https://example.com/"onmouseover="alert(1))`,
+			want:   `<p><a href="https://example.com/%22onmouseover=%22alert%281` + linkAttrs + `x</a>)</p>`,
+		},
+		{
+			name:   "ampersand in href is escaped",
+			source: "[x](https://example.com/?a=1&b=2)",
+			want:   `<p><a href="https://example.com/?a=1&amp;b=2` + linkAttrs + `x</a></p>`,
+		},
+		{
+			name:   "link label is escaped",
+			source: "[<b>x</b>](https://example.com)",
+			want:   `<p><a href="https://example.com` + linkAttrs + `&lt;b&gt;x&lt;/b&gt;</a></p>`,
+		},
+		{
+			name:   "emphasis",
+			source: "*a* **b** _c_ snake_case_name",
+			want:   "<p><em>a</em> <strong>b</strong> <em>c</em> snake_case_name</p>",
+		},
+		{
+			name:   "unclosed emphasis is literal",
+			source: "**a *b",
+			want:   "<p>**a *b</p>",
+		},
+		{
+			name:   "backslash escape",
+			source: `\*not em\*`,
+			want:   "<p>*not em*</p>",
+		},
+		{
+			name:   "lists",
+			source: "- a\n- b\n\n3. c\n4. d",
+			want:   "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n<ol start=\"3\">\n<li>c</li>\n<li>d</li>\n</ol>",
+		},
+		{
+			name:   "block quote",
+			source: "> quoted\n\nafter",
+			want:   "<blockquote>\n<p>quoted</p>\n</blockquote>\n<p>after</p>",
+		},
+		{
+			name:   "spoiler",
+			source: "before [spoiler]he dies[/spoiler] after",
+			want:   `<p>before <span class="spoiler">he dies</span> after</p>`,
+		},
+		{
+			name:   "spoiler tags ignore case",
+			source: "[SPOILER]x[/Spoiler]",
+			want:   `<p><span class="spoiler">x</span></p>`,
+		},
+		{
+			name:   "unclosed spoiler runs to the end across blocks",
+			source: "intro [spoiler]first\n\n- second\n\n> third",
+			want: "<p>intro <span class=\"spoiler\">first</span></p>\n" +
+				"<ul>\n<li><span class=\"spoiler\">second</span></li>\n</ul>\n" +
+				"<blockquote>\n<p><span class=\"spoiler\">third</span></p>\n</blockquote>",
+		},
+		{
+			name:   "spoiler spanning paragraphs closes in a later block",
+			source: "[spoiler]a\n\nb[/spoiler] c",
+			want:   "<p><span class=\"spoiler\">a</span></p>\n<p><span class=\"spoiler\">b</span> c</p>",
+		},
+		{
+			name:   "crlf line endings",
+			source: "a\r\nb",
+			want:   "<p>a<br>\nb</p>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.source); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSnippet(t *testing.T) {
+	placeholder := `<span class="spoiler">[spoiler]</span>`
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "spoiler replaced",
+			source: "before [spoiler]he dies[/spoiler] after",
+			want:   "<p>before " + placeholder + " after</p>",
+		},
+		{
+			name:   "unclosed spoiler across blocks",
+			source: "intro [spoiler]first\n\n- second",
+			want:   "<p>intro " + placeholder + "</p>\n<ul>\n<li>" + placeholder + "</li>\n</ul>",
+		},
+		{
+			name:   "links reduced to their label",
+			source: "see [site](https://example.com)",
+			want:   "<p>see site</p>",
+		},
+		{
+			name:   "link inside spoiler",
+			source: "[spoiler][secret](https://example.com/secret)[/spoiler]",
+			want:   "<p>" + placeholder + "</p>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderSnippet(tt.source); got != tt.want {
+				t.Errorf("RenderSnippet(%q)\n got %q\nwant %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSnippetHidesSpoilerText(t *testing.T) {
+	secret := "Snape kills Dumbledore"
+	for _, source := range []string{
+		"[spoiler]" + secret + "[/spoiler]",
+		"[Spoiler]" + secret + "[/SPOILER]",
+		"ok [spoiler]\n\n" + secret,
+		"> [spoiler]quoted\n> " + secret,
+		"- a [spoiler]b\n- " + secret + "\n\nafter",
+		"[spoiler]**" + secret + "**[/spoiler]",
+		"[spoiler][spoiler]" + secret + "[/spoiler]",
+	} {
+		if got := RenderSnippet(source); strings.Contains(got, "Snape") || strings.Contains(got, "Dumbledore") {
+			t.Errorf("RenderSnippet(%q) leaked spoiler text: %q", source, got)
+		}
+	}
+}
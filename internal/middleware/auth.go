@@ -74,3 +74,52 @@ func GetUserFromContext(r *http.Request) (*models.Claims, bool) {
 	claims, ok := r.Context().Value(UserContextKey).(*models.Claims)
 	return claims, ok
 }
+
+var roleLookup func(userID int) (string, error)
+
+// SetRoleLookup makes role checks use the user's current role instead of the
+// one in their token, so a demotion takes effect before the token expires.
+func SetRoleLookup(lookup func(userID int) (string, error)) {
+	roleLookup = lookup
+}
+
+// HasRole reports whether the request's user currently has at least the given
+// role. It must run inside AuthMiddleware so the claims are on the context.
+func HasRole(r *http.Request, required string) (bool, error) {
+	claims, ok := GetUserFromContext(r)
+	if !ok {
+		return false, nil
+	}
+	role := claims.Role
+	if roleLookup != nil {
+		var err error
+		if role, err = roleLookup(claims.UserID); err != nil {
+			return false, err
+		}
+	}
+	return models.HasRole(role, required), nil
+}
+
+// RequireRole rejects requests whose user is below the given role. It must run
+// inside AuthMiddleware so the claims are already on the context.
+func RequireRole(role string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := GetUserFromContext(r); !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			allowed, err := HasRole(r, role)
+			if err != nil {
+				log.Printf("Role lookup error: %v", err)
+				http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				http.Error(w, "Insufficient permissions", http.StatusForbidden)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	AuthorRoleAuthor      = "author"
+	AuthorRoleTranslator  = "translator"
+	AuthorRoleEditor      = "editor"
+	AuthorRoleIllustrator = "illustrator"
+)
+
+var ValidAuthorRoles = map[string]bool{
+	AuthorRoleAuthor:      true,
+	AuthorRoleTranslator:  true,
+	AuthorRoleEditor:      true,
+	AuthorRoleIllustrator: true,
+}
+
+type Author struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuthorDetail is the author page header: community aggregates across all of the author's books.
+// ViewerShelves maps reading status to the viewer's book IDs and is only set for logged-in viewers.
+type AuthorDetail struct {
+	Author
+	BookCount     int              `json:"book_count"`
+	AverageRating float64          `json:"average_rating"`
+	RatingCount   int              `json:"rating_count"`
+	ReaderCount   int              `json:"reader_count"`
+	ViewerShelves map[string][]int `json:"viewer_shelves,omitempty"`
+}
+
+// AuthorBook is one entry in an author's bibliography.
+type AuthorBook struct {
+	BookID        int     `json:"book_id"`
+	Title         string  `json:"title"`
+	CoverURL      string  `json:"cover_url,omitempty"`
+	PublishedYear int     `json:"published_year,omitempty"`
+	Role          string  `json:"role"`
+	AverageRating float64 `json:"average_rating"`
+	RatingCount   int     `json:"rating_count"`
+	ViewerStatus  string  `json:"viewer_status,omitempty"`
+}
+
+// BookAuthor is an author credited on a specific book.
+type BookAuthor struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// AuthorCredit is how clients name an author when writing a book.
+type AuthorCredit struct {
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"`
+}
+
+// Mirrors the split used by migration 000015: ";", "&", "and" and commas, except before Jr./Sr./II-style suffixes.
+var authorSeparator = regexp.MustCompile(`(?i)\s*(?:;|&|\band\b|,)\s*`)
+var authorSuffix = regexp.MustCompile(`(?i)^(jr|sr|ii|iii|iv)\.?$`)
+
+// SplitAuthorNames splits a legacy "A, B & C" author string into individual names.
+func SplitAuthorNames(author string) []string {
+	names := []string{}
+	for _, part := range authorSeparator.Split(author, -1) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if authorSuffix.MatchString(part) && len(names) > 0 {
+			names[len(names)-1] += ", " + part
+			continue
+		}
+		names = append(names, part)
+	}
+	return names
+}
+
+// NormaliseAuthorCredits returns a cleaned credit list, falling back to splitting the legacy author string.
+// Empty roles default to "author" and duplicate name/role pairs are dropped.
+func NormaliseAuthorCredits(credits []AuthorCredit, legacy string) []AuthorCredit {
+	if len(credits) == 0 {
+		for _, name := range SplitAuthorNames(legacy) {
+			credits = append(credits, AuthorCredit{Name: name})
+		}
+	}
+	seen := map[string]bool{}
+	result := []AuthorCredit{}
+	for _, c := range credits {
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name == "" {
+			continue
+		}
+		if c.Role == "" {
+			c.Role = AuthorRoleAuthor
+		}
+		key := strings.ToLower(c.Name) + "|" + c.Role
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, c)
+	}
+	return result
+}
+
+// AuthorDisplayName builds the books.author display string from the primary authors,
+// or from everyone credited if there is no one with the author role.
+func AuthorDisplayName(credits []AuthorCredit) string {
+	names := []string{}
+	for _, c := range credits {
+		if c.Role == AuthorRoleAuthor {
+			names = append(names, c.Name)
+		}
+	}
+	if len(names) == 0 {
+		for _, c := range credits {
+			names = append(names, c.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -3,31 +3,66 @@ package models
 import "time"
 
 type Book struct {
-	ID            int       `json:"id"`
-	Title         string    `json:"title"`
-	Author        string    `json:"author"`
-	ISBN          string    `json:"isbn,omitempty"`
-	Description   string    `json:"description,omitempty"`
-	PublishedYear int       `json:"published_year,omitempty"`
-	CoverURL      string    `json:"cover_url,omitempty"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            int               `json:"id"`
+	Title         string            `json:"title"`
+	Author        string            `json:"author"`
+	Authors       []BookAuthor      `json:"authors"`
+	ISBN          string            `json:"isbn,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	PublishedYear int               `json:"published_year,omitempty"`
+	CoverURL      string            `json:"cover_url,omitempty"`
+	Thumbnails    map[string]string `json:"thumbnails,omitempty"`
+	WorkID        int               `json:"work_id"`
+	Format        string            `json:"format,omitempty"`
+	Publisher     string            `json:"publisher,omitempty"`
+	PageCount     int               `json:"page_count,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
 }
 
+// CreateBookRequest accepts either a structured Authors list or the legacy Author string,
+// which is split on separators like ";", "&" and "and". Without a WorkID the book starts a new work.
 type CreateBookRequest struct {
+	Title         string         `json:"title"`
+	Author        string         `json:"author"`
+	Authors       []AuthorCredit `json:"authors,omitempty"`
+	ISBN          string         `json:"isbn,omitempty"`
+	Description   string         `json:"description,omitempty"`
+	PublishedYear int            `json:"published_year,omitempty"`
+	CoverURL      string         `json:"cover_url,omitempty"`
+	WorkID        int            `json:"work_id,omitempty"`
+	Format        string         `json:"format,omitempty"`
+	Publisher     string         `json:"publisher,omitempty"`
+	PageCount     int            `json:"page_count,omitempty"`
+}
+
+type UpdateBookRequest struct {
+	Title         *string         `json:"title,omitempty"`
+	Author        *string         `json:"author,omitempty"`
+	Authors       *[]AuthorCredit `json:"authors,omitempty"`
+	ISBN          *string         `json:"isbn,omitempty"`
+	Description   *string         `json:"description,omitempty"`
+	PublishedYear *int            `json:"published_year,omitempty"`
+	CoverURL      *string         `json:"cover_url,omitempty"`
+	WorkID        *int            `json:"work_id,omitempty"`
+	Format        *string         `json:"format,omitempty"`
+	Publisher     *string         `json:"publisher,omitempty"`
+	PageCount     *int            `json:"page_count,omitempty"`
+}
+
+// Edition is a compact view of another book row that belongs to the same work.
+type Edition struct {
+	BookID        int    `json:"book_id"`
 	Title         string `json:"title"`
-	Author        string `json:"author"`
 	ISBN          string `json:"isbn,omitempty"`
-	Description   string `json:"description,omitempty"`
+	Format        string `json:"format,omitempty"`
+	Publisher     string `json:"publisher,omitempty"`
 	PublishedYear int    `json:"published_year,omitempty"`
 	CoverURL      string `json:"cover_url,omitempty"`
 }
 
-type UpdateBookRequest struct {
-	Title         *string `json:"title,omitempty"`
-	Author        *string `json:"author,omitempty"`
-	ISBN          *string `json:"isbn,omitempty"`
-	Description   *string `json:"description,omitempty"`
-	PublishedYear *int    `json:"published_year,omitempty"`
-	CoverURL      *string `json:"cover_url,omitempty"`
+// SearchHighlights holds ts_headline snippets with matched terms wrapped in <mark> tags.
+type SearchHighlights struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
 }
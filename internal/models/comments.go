@@ -2,11 +2,14 @@ package models
 
 import "time"
 
+// Comment is a reply to a review. Text is Markdown and TextHTML its sanitized
+// rendering.
 type Comment struct {
 	ID        int       `json:"id"`
 	UserID    int       `json:"user_id"`
 	RatingID  int       `json:"rating_id"`
 	Text      string    `json:"text"`
+	TextHTML  string    `json:"text_html"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
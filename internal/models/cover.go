@@ -0,0 +1,47 @@
+package models
+
+import "strings"
+
+// CoverURLPrefix is where locally stored covers are served from. Each stored
+// cover lives at CoverURLPrefix + "<content hash>/<size>.jpg".
+const CoverURLPrefix = "/covers/"
+
+// CoverSizes maps thumbnail names to their maximum width in pixels.
+var CoverSizes = []struct {
+	Name  string
+	Width int
+}{
+	{"small", 96},
+	{"medium", 240},
+	{"large", 480},
+}
+
+// CoverDefaultSize is the thumbnail stored in books.cover_url.
+const CoverDefaultSize = "large"
+
+func CoverKey(hash, size string) string {
+	return hash + "/" + size + ".jpg"
+}
+
+// CoverThumbnails returns the URL of every thumbnail size for a locally stored
+// cover, or nil for remote or empty cover URLs.
+func CoverThumbnails(coverURL string) map[string]string {
+	if !strings.HasPrefix(coverURL, CoverURLPrefix) {
+		return nil
+	}
+	hash, _, ok := strings.Cut(strings.TrimPrefix(coverURL, CoverURLPrefix), "/")
+	if !ok || hash == "" {
+		return nil
+	}
+	thumbnails := make(map[string]string, len(CoverSizes))
+	for _, size := range CoverSizes {
+		thumbnails[size.Name] = CoverURLPrefix + CoverKey(hash, size.Name)
+	}
+	return thumbnails
+}
+
+// RemoteCover is a book whose cover is still hotlinked from another site.
+type RemoteCover struct {
+	BookID int
+	URL    string
+}
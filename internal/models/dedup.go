@@ -0,0 +1,73 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	leadingArticleRe  = regexp.MustCompile(`^(the|a|an)\s+`)
+	trailingArticleRe = regexp.MustCompile(`,\s*(the|a|an)$`)
+	// \w only matches ASCII, which would strip accented and non-Latin letters
+	punctuationRe = regexp.MustCompile(`[^\p{L}\p{N}\p{M}\s]`)
+	whitespaceRe  = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeBookKey reduces a title and author to a key that is equal for
+// trivially different spellings of the same book, e.g. "The Hobbit",
+// "Hobbit, The" and "The Hobbit: or There and Back Again".
+func NormalizeBookKey(title, author string) string {
+	title = strings.ToLower(strings.TrimSpace(title))
+
+	// Remove subtitle (after : or " - ")
+	if idx := strings.Index(title, ":"); idx > 0 {
+		title = title[:idx]
+	}
+	if idx := strings.Index(title, " - "); idx > 0 {
+		title = title[:idx]
+	}
+
+	// Remove leading and library-style trailing articles
+	title = strings.TrimSpace(title)
+	title = trailingArticleRe.ReplaceAllString(title, "")
+	title = leadingArticleRe.ReplaceAllString(title, "")
+
+	// Remove punctuation and collapse whitespace
+	title = punctuationRe.ReplaceAllString(title, "")
+	title = whitespaceRe.ReplaceAllString(title, " ")
+	title = strings.TrimSpace(title)
+
+	// Normalize author
+	author = strings.ToLower(author)
+	author = punctuationRe.ReplaceAllString(author, "")
+	author = whitespaceRe.ReplaceAllString(author, " ")
+	author = strings.TrimSpace(author)
+
+	return title + "|" + author
+}
+
+// DuplicateCandidate is a book that may be the same edition as another one.
+// Reasons lists the signals that matched: "isbn", "normalized_key" or "title_similarity".
+type DuplicateCandidate struct {
+	BookID          int      `json:"book_id"`
+	Title           string   `json:"title"`
+	Author          string   `json:"author"`
+	ISBN            string   `json:"isbn,omitempty"`
+	PublishedYear   int      `json:"published_year,omitempty"`
+	CoverURL        string   `json:"cover_url,omitempty"`
+	RatingCount     int      `json:"rating_count"`
+	TitleSimilarity float64  `json:"title_similarity"`
+	Reasons         []string `json:"reasons"`
+}
+
+// DuplicateGroup is a set of books that share a normalized key or an ISBN.
+type DuplicateGroup struct {
+	Reason string               `json:"reason"`
+	Key    string               `json:"key"`
+	Books  []DuplicateCandidate `json:"books"`
+}
+
+type MergeBooksRequest struct {
+	WinnerID int `json:"winner_id"`
+	LoserID  int `json:"loser_id"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "testing"
+
+func TestNormalizeBookKey(t *testing.T) {
+	tests := []struct {
+		title, author string
+		want          string
+	}{
+		{"The Hobbit", "J.R.R. Tolkien", "hobbit|jrr tolkien"},
+		{"Hobbit, The", "J. R. R. Tolkien", "hobbit|j r r tolkien"},
+		{"The Hobbit: or There and Back Again", "J.R.R. Tolkien", "hobbit|jrr tolkien"},
+		{"Les Misérables", "Victor Hugo", "les misérables|victor hugo"},
+		{"Les Misérables!", "Victor  Hugo", "les misérables|victor hugo"},
+		{"Преступление и наказание", "Фёдор Достоевский", "преступление и наказание|фёдор достоевский"},
+		{"ノルウェイの森", "村上 春樹", "ノルウェイの森|村上 春樹"},
+		{"1984", "George Orwell", "1984|george orwell"},
+		{"?!", "Someone", "|someone"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeBookKey(tt.title, tt.author); got != tt.want {
+			t.Errorf("NormalizeBookKey(%q, %q) = %q, want %q", tt.title, tt.author, got, tt.want)
+		}
+	}
+
+	// Different non-Latin titles by one author mustn't collapse to one key
+	a := NormalizeBookKey("Преступление и наказание", "Фёдор Достоевский")
+	b := NormalizeBookKey("Идиот", "Фёдор Достоевский")
+	if a == b {
+		t.Errorf("distinct Cyrillic titles share key %q", a)
+	}
+}
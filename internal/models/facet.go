@@ -0,0 +1,42 @@
+package models
+
+// BookFilter holds the combinable filters for the book listing.
+type BookFilter struct {
+	Search string
+	// Genres match including their subgenres; MatchAllGenres requires every
+	// genre instead of any of them.
+	Genres         []string
+	MatchAllGenres bool
+	Tags           []string
+	YearFrom       *int
+	YearTo         *int
+	MinAverage     *float64
+	MinRatingCount int
+	// ExcludeShelvedBy hides works the user already has on any shelf.
+	ExcludeShelvedBy *int
+}
+
+type GenreFacet struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type DecadeFacet struct {
+	Decade int `json:"decade"`
+	Count  int `json:"count"`
+}
+
+// BookFacets counts the books matching a filter. Genre counts include books
+// filed under subgenres.
+type BookFacets struct {
+	Total   int           `json:"total"`
+	Genres  []GenreFacet  `json:"genres"`
+	Decades []DecadeFacet `json:"decades"`
+}
+
+type BookListResponse struct {
+	Books      []*BookWithGenres `json:"books"`
+	Facets     *BookFacets       `json:"facets,omitempty"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
@@ -1,14 +1,40 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
+var (
+	ErrGenreExists = errors.New("genre already exists")
+	ErrGenreCycle  = errors.New("genre cannot be nested under itself or its subgenres")
+)
+
+// Genre may be nested under a parent, e.g. Epic Fantasy under Fantasy.
 type Genre struct {
 	ID        int       `json:"id"`
 	Name      string    `json:"name"`
+	ParentID  *int      `json:"parent_id,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type CreateGenreRequest struct {
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id,omitempty"`
+}
+
+// UpdateGenreRequest renames and/or moves a genre. A ParentID of 0 makes it top level.
+type UpdateGenreRequest struct {
+	Name     *string `json:"name,omitempty"`
+	ParentID *int    `json:"parent_id,omitempty"`
+}
+
 type BookWithGenres struct {
 	Book
-	Genres []Genre `json:"genres"`
+	Genres     []Genre            `json:"genres"`
+	Editions   []Edition          `json:"editions,omitempty"`
+	Series     []SeriesMembership `json:"series,omitempty"`
+	Tags       []BookTag          `json:"tags"`
+	SearchRank float64            `json:"search_rank,omitempty"`
+	Highlights *SearchHighlights  `json:"highlights,omitempty"`
 }
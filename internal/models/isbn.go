@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidISBN = errors.New("invalid isbn")
+	ErrISBNExists  = errors.New("isbn already exists")
+)
+
+var isbnSeparators = strings.NewReplacer("-", "", " ", "")
+
+// cleanISBN strips Goodreads' ="..." spreadsheet wrapping, hyphens and spaces.
+func cleanISBN(raw string) string {
+	s := strings.TrimSpace(raw)
+	s = strings.TrimPrefix(s, "=")
+	s = strings.Trim(s, `"`)
+	return strings.ToUpper(isbnSeparators.Replace(s))
+}
+
+// NormalizeISBN validates an ISBN-10 or ISBN-13 and returns it as a bare
+// ISBN-13. An empty input returns "" with no error so ISBN stays optional.
+func NormalizeISBN(raw string) (string, error) {
+	s := cleanISBN(raw)
+	switch {
+	case s == "":
+		return "", nil
+	case len(s) == 10 && validISBN10(s):
+		return isbn13FromBody("978" + s[:9]), nil
+	case len(s) == 13 && validISBN13(s):
+		return s, nil
+	}
+	return "", ErrInvalidISBN
+}
+
+// ISBNMatchKey returns a comparable form of an ISBN so that the ISBN-10 and
+// ISBN-13 of the same edition are equal. Invalid values are returned cleaned.
+func ISBNMatchKey(isbn string) string {
+	if normalized, err := NormalizeISBN(isbn); err == nil {
+		return normalized
+	}
+	return cleanISBN(isbn)
+}
+
+// ISBNForms lists the spellings an ISBN may be stored under: as given, as
+// ISBN-13 and, for 978-prefixed numbers, as ISBN-10.
+func ISBNForms(isbn string) []string {
+	forms := []string{isbn}
+	key := ISBNMatchKey(isbn)
+	if key == "" {
+		return forms
+	}
+	forms = append(forms, key)
+	if len(key) == 13 && strings.HasPrefix(key, "978") {
+		forms = append(forms, isbn10FromBody(key[3:12]))
+	}
+	return forms
+}
+
+func validISBN10(s string) bool {
+	sum := 0
+	for i, r := range s {
+		var d int
+		switch {
+		case r >= '0' && r <= '9':
+			d = int(r - '0')
+		case r == 'X' && i == 9:
+			d = 10
+		default:
+			return false
+		}
+		sum += (10 - i) * d
+	}
+	return sum%11 == 0
+}
+
+func validISBN13(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	if !strings.HasPrefix(s, "978") && !strings.HasPrefix(s, "979") {
+		return false
+	}
+	return isbn13FromBody(s[:12]) == s
+}
+
+// isbn10FromBody appends the ISBN-10 check character to a 9 digit body.
+func isbn10FromBody(body string) string {
+	sum := 0
+	for i, r := range body {
+		sum += (10 - i) * int(r-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X"
+	}
+	return body + string(rune('0'+check))
+}
+
+// isbn13FromBody appends the ISBN-13 check digit to a 12 digit body.
+func isbn13FromBody(body string) string {
+	sum := 0
+	for i, r := range body {
+		d := int(r - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return body + string(rune('0'+(10-sum%10)%10))
+}
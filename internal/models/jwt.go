@@ -13,6 +13,7 @@ type Claims struct {
 	UserID   int    `json:"user_id"`
 	Email    string `json:"email"`
 	Username string `json:"username"`
+	Role     string `json:"role"`
 	jwt.RegisteredClaims
 }
 
@@ -21,6 +22,7 @@ func GenerateToken(user *User) (string, error) {
 		UserID:   user.ID,
 		Email:    user.Email,
 		Username: user.Username,
+		Role:     user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
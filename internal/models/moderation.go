@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	EditActionUpdate = "update"
+	EditActionDelete = "delete"
+
+	EditStatusPending  = "pending"
+	EditStatusApproved = "approved"
+	EditStatusRejected = "rejected"
+)
+
+// PendingBookEdit is a catalog change submitted by a user without editing rights.
+// Changes is only set for updates.
+type PendingBookEdit struct {
+	ID         int                `json:"id"`
+	BookID     int                `json:"book_id"`
+	BookTitle  string             `json:"book_title"`
+	UserID     int                `json:"user_id"`
+	Username   string             `json:"username"`
+	Action     string             `json:"action"`
+	Changes    *UpdateBookRequest `json:"changes,omitempty"`
+	Status     string             `json:"status"`
+	ReviewedBy *int               `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time         `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the last row of a page by its sort key and id. Value holds a
+// numeric sort key (search rank, likes, rating) for orderings that put one
+// ahead of the timestamp.
+type Cursor struct {
+	Value float64   `json:"v,omitempty"`
+	Time  time.Time `json:"t"`
+	ID    int       `json:"id"`
+}
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// PageParams selects a page either by offset or by the cursor of the previous
+// page. A Limit of 0 returns every row.
+type PageParams struct {
+	Limit  int
+	Offset int
+	After  *Cursor
+}
+
+// Page wraps a list response for clients paginating by cursor.
+type Page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []Cursor{
+		{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), ID: 1},
+		// Postgres timestamps carry microseconds
+		{Time: time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC), ID: 42},
+		{Value: 12, Time: time.Date(2023, 12, 31, 23, 59, 59, 999999000, time.UTC), ID: 7},
+		// A search rank as Postgres returns it, not representable as a short decimal
+		{Value: float64(float32(0.0607927)), Time: time.Unix(1700000000, 0).UTC(), ID: 3},
+		{Value: math.Nextafter(0.1, 1), Time: time.Unix(0, 0).UTC(), ID: math.MaxInt32},
+	}
+	for _, want := range tests {
+		got, err := DecodeCursor(want.Encode())
+		if err != nil {
+			t.Fatalf("DecodeCursor(%+v): %v", want, err)
+		}
+		if got.Value != want.Value || !got.Time.Equal(want.Time) || got.ID != want.ID {
+			t.Errorf("round trip of %+v gave %+v", want, *got)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	for _, token := range []string{
+		"",
+		"not base64!",
+		"bm90IGpzb24",                     // "not json"
+		Cursor{Time: time.Now()}.Encode(), // missing id
+	} {
+		if _, err := DecodeCursor(token); err != ErrInvalidCursor {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", token, err)
+		}
+	}
+}
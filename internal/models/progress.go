@@ -0,0 +1,69 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	ErrInvalidProgress = errors.New("progress needs a page or a percent between 0 and 100")
+	ErrPagePastEnd     = errors.New("page is past the end of the book")
+)
+
+// ReadingProgress is one progress update on a read. When the book's page
+// count is known, whichever of Page and Percent wasn't given is filled in.
+type ReadingProgress struct {
+	ID          int       `json:"id"`
+	SessionID   int       `json:"session_id"`
+	BookID      int       `json:"book_id"`
+	Page        *int      `json:"page,omitempty"`
+	Percent     *float64  `json:"percent,omitempty"`
+	PageCount   int       `json:"page_count,omitempty"`
+	Comment     string    `json:"comment,omitempty"`
+	CommentHTML string    `json:"comment_html,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ProgressRequest struct {
+	Page    *int     `json:"page"`
+	Percent *float64 `json:"percent"`
+	Comment string   `json:"comment"`
+}
+
+// CurrentRead is a book the user is reading, with their latest progress if
+// they've posted any.
+type CurrentRead struct {
+	BookID    int              `json:"book_id"`
+	Title     string           `json:"title"`
+	Author    string           `json:"author"`
+	CoverURL  string           `json:"cover_url,omitempty"`
+	PageCount int              `json:"page_count,omitempty"`
+	StartedAt *time.Time       `json:"started_at,omitempty"`
+	Progress  *ReadingProgress `json:"progress,omitempty"`
+}
+
+// ResolveProgress validates a progress position and, when the page count is
+// known, derives the page from the percent or the percent from the page.
+func ResolveProgress(page *int, percent *float64, pageCount int) (*int, *float64, error) {
+	if page == nil && percent == nil {
+		return nil, nil, ErrInvalidProgress
+	}
+	if (page != nil && *page < 0) || (percent != nil && (*percent < 0 || *percent > 100)) {
+		return nil, nil, ErrInvalidProgress
+	}
+	if pageCount <= 0 {
+		return page, percent, nil
+	}
+	if page != nil && *page > pageCount {
+		return nil, nil, ErrPagePastEnd
+	}
+	if percent == nil {
+		p := math.Round(float64(*page)*10000/float64(pageCount)) / 100
+		percent = &p
+	} else if page == nil {
+		p := int(math.Round(*percent * float64(pageCount) / 100))
+		page = &p
+	}
+	return page, percent, nil
+}
@@ -1,16 +1,96 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"strings"
+	"time"
+)
 
+// Visibility levels for a reading entry
+const (
+	VisibilityPublic    = "public"
+	VisibilityFollowers = "followers"
+	VisibilityPrivate   = "private"
+)
+
+// ValidVisibility reports whether v is a visibility level.
+func ValidVisibility(v string) bool {
+	return v == VisibilityPublic || v == VisibilityFollowers || v == VisibilityPrivate
+}
+
+var ErrInvalidLanguage = errors.New("language must be a language code such as en or pt-BR")
+
+// NormalizeLanguage reduces a language tag such as "pt-BR" to its lowercase
+// primary subtag, "pt". An empty tag stays empty.
+func NormalizeLanguage(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return "", nil
+	}
+	primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	if len(primary) < 2 || len(primary) > 3 {
+		return "", ErrInvalidLanguage
+	}
+	for _, c := range primary {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return "", ErrInvalidLanguage
+		}
+	}
+	return strings.ToLower(primary), nil
+}
+
+// EntryPrivacy is the privacy part of a change to a reading entry. An empty
+// Visibility or nil PrivateNotes leaves the entry's current value.
+type EntryPrivacy struct {
+	Visibility   string
+	PrivateNotes *string
+}
+
+// Rating is a user's reading entry for a book. StartedAt, FinishedAt and the
+// did-not-finish StoppedAt fields come from the read in progress, or else the
+// read that ended most recently.
+//
+// Review is the Markdown source and ReviewHTML its sanitized rendering, with
+// spoilers in <span class="spoiler">. ReviewSegments is the source split into
+// plain and spoiler sections. Spoiler
+// marks the whole review as a spoiler, and SpoilersRevealed is set when the
+// viewer has finished the book and asked to see spoilers for it. EditedAt is
+// set once a written review has been changed; earlier versions are kept as
+// ReviewRevisions. PrivateNotes are only ever returned to the entry's owner.
 type Rating struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	BookID    int       `json:"book_id"`
-	Rating    int       `json:"rating"`
-	Review    string    `json:"review,omitempty"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               int             `json:"id"`
+	UserID           int             `json:"user_id"`
+	BookID           int             `json:"book_id"`
+	Rating           int             `json:"rating"`
+	Review           string          `json:"review,omitempty"`
+	ReviewHTML       string          `json:"review_html,omitempty"`
+	Spoiler          bool            `json:"spoiler"`
+	Language         string          `json:"language,omitempty"`
+	ReviewSegments   []ReviewSegment `json:"review_segments,omitempty"`
+	SpoilersRevealed bool            `json:"spoilers_revealed,omitempty"`
+	EditedAt         *time.Time      `json:"edited_at,omitempty"`
+	Status           string          `json:"status"`
+	Visibility       string          `json:"visibility,omitempty"`
+	PrivateNotes     string          `json:"private_notes,omitempty"`
+	ReadCount        int             `json:"read_count,omitempty"`
+	StartedAt        *time.Time      `json:"started_at,omitempty"`
+	FinishedAt       *time.Time      `json:"finished_at,omitempty"`
+	StoppedAt        *time.Time      `json:"stopped_at,omitempty"`
+	StoppedAtPage    *int            `json:"stopped_at_page,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// ReviewRevision is an earlier version of a review. WrittenAt is when that
+// version was saved and CreatedAt when it was replaced.
+type ReviewRevision struct {
+	ID         int       `json:"id"`
+	RatingID   int       `json:"rating_id"`
+	Review     string    `json:"review"`
+	ReviewHTML string    `json:"review_html"`
+	Spoiler    bool      `json:"spoiler"`
+	WrittenAt  time.Time `json:"written_at"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type RatingWithUser struct {
@@ -18,22 +98,38 @@ type RatingWithUser struct {
 	Username string `json:"username"`
 }
 
+// ReviewFilter narrows the ratings listed for a book. Zero values don't
+// filter. Following needs a logged-in viewer.
+type ReviewFilter struct {
+	HasReview bool
+	MinRating int
+	MaxRating int
+	Following bool
+	Language  string
+}
+
 type BookRatingStats struct {
 	BookID        int               `json:"book_id"`
 	AverageRating float64           `json:"average_rating"`
 	TotalRatings  int               `json:"total_ratings"`
 	Ratings       []RatingWithLikes `json:"ratings"`
+	NextCursor    string            `json:"next_cursor,omitempty"`
 }
 
+// FeedItem is either a reading entry (Type "rating") or a progress update
+// (Type "progress"). For progress updates ID is the update's, not the entry's,
+// and the update itself is in Progress.
 type FeedItem struct {
 	Rating
-	Username     string `json:"username"`
-	BookTitle    string `json:"book_title"`
-	BookAuthor   string `json:"book_author"`
-	BookCover    string `json:"book_cover"`
-	LikeCount    int    `json:"like_count"`
-	LikedByUser  bool   `json:"liked_by_user"`
-	CommentCount int    `json:"comment_count"`
+	Type         string           `json:"type"`
+	Username     string           `json:"username"`
+	BookTitle    string           `json:"book_title"`
+	BookAuthor   string           `json:"book_author"`
+	BookCover    string           `json:"book_cover"`
+	LikeCount    int              `json:"like_count"`
+	LikedByUser  bool             `json:"liked_by_user"`
+	CommentCount int              `json:"comment_count"`
+	Progress     *ReadingProgress `json:"progress,omitempty"`
 }
 
 type RatingWithLikes struct {
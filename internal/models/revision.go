@@ -0,0 +1,105 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// FieldChange holds the JSON encoded value of one book field before and after an edit.
+type FieldChange struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// BookRevision records a single edit to a book. Changes is keyed by the
+// UpdateBookRequest JSON field name, so a revision can be turned back into a request.
+type BookRevision struct {
+	ID           int                    `json:"id"`
+	BookID       int                    `json:"book_id"`
+	UserID       *int                   `json:"user_id,omitempty"`
+	Username     string                 `json:"username,omitempty"`
+	Changes      map[string]FieldChange `json:"changes"`
+	RevertedFrom *int                   `json:"reverted_from,omitempty"`
+	CreatedAt    time.Time              `json:"created_at"`
+}
+
+// DiffBookUpdate compares the current book with an update and returns only the
+// fields whose value actually changes. credits are the normalised credits the
+// update will write, or nil if authors aren't being changed.
+func DiffBookUpdate(current *Book, req UpdateBookRequest, credits []AuthorCredit) (map[string]FieldChange, error) {
+	changes := map[string]FieldChange{}
+	add := func(field string, old, new interface{}) error {
+		oldJSON, err := json.Marshal(old)
+		if err != nil {
+			return err
+		}
+		newJSON, err := json.Marshal(new)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(oldJSON, newJSON) {
+			changes[field] = FieldChange{Old: oldJSON, New: newJSON}
+		}
+		return nil
+	}
+
+	var err error
+	if req.Title != nil {
+		err = add("title", current.Title, *req.Title)
+	}
+	if err == nil && credits != nil {
+		err = add("authors", currentCredits(current.Authors), credits)
+	}
+	if err == nil && req.ISBN != nil {
+		err = add("isbn", current.ISBN, *req.ISBN)
+	}
+	if err == nil && req.Description != nil {
+		err = add("description", current.Description, *req.Description)
+	}
+	if err == nil && req.PublishedYear != nil {
+		err = add("published_year", current.PublishedYear, *req.PublishedYear)
+	}
+	if err == nil && req.CoverURL != nil {
+		err = add("cover_url", current.CoverURL, *req.CoverURL)
+	}
+	if err == nil && req.WorkID != nil {
+		err = add("work_id", current.WorkID, *req.WorkID)
+	}
+	if err == nil && req.Format != nil {
+		err = add("format", current.Format, *req.Format)
+	}
+	if err == nil && req.Publisher != nil {
+		err = add("publisher", current.Publisher, *req.Publisher)
+	}
+	if err == nil && req.PageCount != nil {
+		err = add("page_count", current.PageCount, *req.PageCount)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// RevertRequest builds an update that restores every field this revision changed.
+func (rev *BookRevision) RevertRequest() (UpdateBookRequest, error) {
+	old := make(map[string]json.RawMessage, len(rev.Changes))
+	for field, change := range rev.Changes {
+		old[field] = change.Old
+	}
+	var req UpdateBookRequest
+	data, err := json.Marshal(old)
+	if err != nil {
+		return req, err
+	}
+	err = json.Unmarshal(data, &req)
+	return req, err
+}
+
+func currentCredits(authors []BookAuthor) []AuthorCredit {
+	credits := make([]AuthorCredit, 0, len(authors))
+	for _, a := range authors {
+		credits = append(credits, AuthorCredit{Name: a.Name, Role: a.Role})
+	}
+	return credits
+}
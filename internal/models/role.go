@@ -0,0 +1,27 @@
+package models
+
+const (
+	RoleUser      = "user"
+	RoleLibrarian = "librarian"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// roleRank orders roles so each one includes the permissions of those below it.
+var roleRank = map[string]int{
+	RoleUser:      0,
+	RoleLibrarian: 1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// HasRole reports whether role is at least required. Unknown or empty roles
+// (e.g. tokens issued before roles existed) are treated as a regular user.
+func HasRole(role, required string) bool {
+	return roleRank[role] >= roleRank[required]
+}
@@ -0,0 +1,10 @@
+package models
+
+type Suggestion struct {
+	Type     string  `json:"type"`
+	ID       int     `json:"id,omitempty"`
+	Label    string  `json:"label"`
+	Detail   string  `json:"detail,omitempty"`
+	CoverURL string  `json:"cover_url,omitempty"`
+	Score    float64 `json:"score"`
+}
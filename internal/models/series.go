@@ -0,0 +1,54 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// MaxSeriesPosition bounds a series position to what the NUMERIC(6, 2)
+// column can hold.
+const MaxSeriesPosition = 10000
+
+// ValidSeriesPosition reports whether p is in [0, MaxSeriesPosition) with at
+// most two decimal places, e.g. 3 or 2.5.
+func ValidSeriesPosition(p float64) bool {
+	if p < 0 || p >= MaxSeriesPosition {
+		return false
+	}
+	hundredths := p * 100
+	return math.Abs(hundredths-math.Round(hundredths)) < 1e-6
+}
+
+type Series struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SeriesEntry is one work in a series, represented by its oldest edition.
+// ViewerStatus is the viewer's furthest status across any edition of the work.
+type SeriesEntry struct {
+	Position      float64 `json:"position"`
+	WorkID        int     `json:"work_id"`
+	BookID        int     `json:"book_id"`
+	Title         string  `json:"title"`
+	Author        string  `json:"author"`
+	CoverURL      string  `json:"cover_url,omitempty"`
+	PublishedYear int     `json:"published_year,omitempty"`
+	ViewerStatus  string  `json:"viewer_status,omitempty"`
+}
+
+type SeriesWithEntries struct {
+	Series
+	Entries    []SeriesEntry `json:"entries"`
+	NextUnread *SeriesEntry  `json:"next_unread,omitempty"`
+}
+
+// SeriesMembership is shown on book detail pages.
+type SeriesMembership struct {
+	SeriesID int     `json:"series_id"`
+	Name     string  `json:"name"`
+	Position float64 `json:"position"`
+}
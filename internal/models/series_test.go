@@ -0,0 +1,26 @@
+package models
+
+import "testing"
+
+func TestValidSeriesPosition(t *testing.T) {
+	tests := []struct {
+		position float64
+		want     bool
+	}{
+		{0, true},
+		{1, true},
+		{2.5, true},
+		{1.1, true},
+		{0.01, true},
+		{9999.99, true},
+		{-1, false},
+		{10000, false},
+		{1.005, false},
+		{9999.999, false},
+	}
+	for _, tt := range tests {
+		if got := ValidSeriesPosition(tt.position); got != tt.want {
+			t.Errorf("ValidSeriesPosition(%v) = %v, want %v", tt.position, got, tt.want)
+		}
+	}
+}
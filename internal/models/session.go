@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidReadDate = errors.New("dates must be YYYY-MM-DD")
+	ErrReadInProgress  = errors.New("a read of this book is already in progress")
+)
+
+// ReadingSession is one read of a book. A read ends either Finished or, when
+// the reader gave up, with StoppedAt; a session with neither is the read in
+// progress. FinishedAt is empty for a finished read whose date isn't known.
+// The entry's rating follows its latest finished session.
+type ReadingSession struct {
+	ID            int        `json:"id"`
+	RatingID      int        `json:"rating_id"`
+	BookID        int        `json:"book_id"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	Finished      bool       `json:"finished"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	StoppedAt     *time.Time `json:"stopped_at,omitempty"`
+	StoppedAtPage *int       `json:"stopped_at_page,omitempty"`
+	Rating        int        `json:"rating"`
+	Notes         string     `json:"notes,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ReadingSessionRequest logs or edits a read. Dates are YYYY-MM-DD; leaving
+// both finished_at and stopped_at empty marks the read as in progress unless
+// finished is set for a read finished on an unknown date.
+type ReadingSessionRequest struct {
+	StartedAt     string `json:"started_at"`
+	Finished      bool   `json:"finished"`
+	FinishedAt    string `json:"finished_at"`
+	StoppedAt     string `json:"stopped_at"`
+	StoppedAtPage *int   `json:"stopped_at_page"`
+	Rating        int    `json:"rating"`
+	Notes         string `json:"notes"`
+}
+
+// ReadDates are the optional dates sent with a rating or read. Unset dates
+// default to today when a read is started, finished or given up on, except
+// that a read with FinishedUndated is finished with no date, as for imports
+// that don't say when a book was read. StoppedAtPage is where a
+// did-not-finish read was left off.
+type ReadDates struct {
+	StartedAt       *time.Time
+	FinishedAt      *time.Time
+	FinishedUndated bool
+	StoppedAt       *time.Time
+	StoppedAtPage   *int
+}
+
+// Finished reports whether the dates describe a finished read.
+func (d ReadDates) Finished() bool {
+	return d.FinishedAt != nil || d.FinishedUndated
+}
+
+// ParseReadDate parses a YYYY-MM-DD date, also accepting RFC 3339 and the
+// YYYY/MM/DD form used in Goodreads exports. An empty string is no date.
+func ParseReadDate(raw string) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006/01/02", time.RFC3339} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+			return &day, nil
+		}
+	}
+	return nil, ErrInvalidReadDate
+}
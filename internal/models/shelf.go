@@ -0,0 +1,68 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const MaxShelfNameLength = 50
+
+var (
+	ErrShelfExists      = errors.New("you already have a shelf with that name")
+	ErrInvalidShelfName = errors.New("shelf names must be 1-50 characters")
+	ErrReservedShelf    = errors.New("that shelf name is a reading status")
+)
+
+// Shelf is a user-defined shelf. Books sit on shelves independently of their
+// reading status, so a finished book can also be on "owned".
+type Shelf struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	BookCount   int       `json:"book_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ShelfBook is a book on a shelf, with the shelf owner's reading status if
+// they have one.
+type ShelfBook struct {
+	BookID   int       `json:"book_id"`
+	Title    string    `json:"title"`
+	Author   string    `json:"author"`
+	CoverURL string    `json:"cover_url,omitempty"`
+	Status   string    `json:"status,omitempty"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// statusShelves maps the Goodreads names of the built-in shelves, plus the
+// usual names people give a did-not-finish shelf, to reading statuses.
+var statusShelves = map[string]string{
+	"read":              "finished_reading",
+	"to-read":           "to_read",
+	"currently-reading": "currently_reading",
+	"did-not-finish":    "did_not_finish",
+	"dnf":               "did_not_finish",
+	"abandoned":         "did_not_finish",
+}
+
+// StatusForShelf returns the reading status a shelf name stands for, if any.
+func StatusForShelf(name string) (string, bool) {
+	status, ok := statusShelves[strings.ToLower(strings.TrimSpace(name))]
+	return status, ok
+}
+
+// NormalizeShelfName trims a shelf name and rejects empty, overlong and
+// reading-status names.
+func NormalizeShelfName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > MaxShelfNameLength {
+		return "", ErrInvalidShelfName
+	}
+	if _, ok := StatusForShelf(name); ok {
+		return "", ErrReservedShelf
+	}
+	return name, nil
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/pulkyeet/BookmarkD/internal/markdown"
+)
+
+// ReviewSegment is a run of review text. Clients should hide spoiler segments
+// until the reader asks to see them.
+type ReviewSegment struct {
+	Text    string `json:"text"`
+	Spoiler bool   `json:"spoiler"`
+}
+
+// ParseReview splits a review on [spoiler]...[/spoiler] markup, read the same
+// way markdown renders it: tags match case-insensitively and an unclosed tag
+// hides the rest of the review. If the whole review is flagged as a spoiler,
+// every segment is marked.
+func ParseReview(review string, spoiler bool) []ReviewSegment {
+	var segments []ReviewSegment
+	inSpoiler := false
+	for review != "" {
+		section, rest, found := markdown.SplitSpoiler(review, inSpoiler)
+		segments = appendSegment(segments, section, spoiler || inSpoiler)
+		review = rest
+		if found {
+			inSpoiler = !inSpoiler
+		}
+	}
+	for _, segment := range segments {
+		if strings.TrimSpace(segment.Text) != "" {
+			return segments
+		}
+	}
+	return nil
+}
+
+// TruncateSegments cuts segments down to at most max characters of text,
+// marking the cut with "...".
+func TruncateSegments(segments []ReviewSegment, max int) []ReviewSegment {
+	truncated := []ReviewSegment{}
+	for _, segment := range segments {
+		text := []rune(segment.Text)
+		if len(text) > max {
+			truncated = append(truncated, ReviewSegment{Text: string(text[:max]) + "...", Spoiler: segment.Spoiler})
+			break
+		}
+		truncated = append(truncated, segment)
+		max -= len(text)
+	}
+	return truncated
+}
+
+// SpoilerFreeText joins segments back into plain text with each spoiler
+// replaced by placeholder.
+func SpoilerFreeText(segments []ReviewSegment, placeholder string) string {
+	var b strings.Builder
+	for _, segment := range segments {
+		if segment.Spoiler {
+			b.WriteString(placeholder)
+		} else {
+			b.WriteString(segment.Text)
+		}
+	}
+	return b.String()
+}
+
+func appendSegment(segments []ReviewSegment, text string, spoiler bool) []ReviewSegment {
+	if text == "" {
+		return segments
+	}
+	if n := len(segments); n > 0 && segments[n-1].Spoiler == spoiler {
+		segments[n-1].Text += text
+		return segments
+	}
+	return append(segments, ReviewSegment{Text: text, Spoiler: spoiler})
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReview(t *testing.T) {
+	tests := []struct {
+		name    string
+		review  string
+		spoiler bool
+		want    []ReviewSegment
+	}{
+		{"empty", "", false, nil},
+		{"whitespace only", "  \n ", false, nil},
+		{"empty spoiler only", "[spoiler][/spoiler]", false, nil},
+		{"plain", "great book", false, []ReviewSegment{{"great book", false}}},
+		{"whole review flagged", "great book", true, []ReviewSegment{{"great book", true}}},
+		{
+			name:   "inline spoiler",
+			review: "good until [spoiler]he dies[/spoiler], then meh",
+			want:   []ReviewSegment{{"good until ", false}, {"he dies", true}, {", then meh", false}},
+		},
+		{
+			name:   "tags ignore case",
+			review: "a [SPOILER]b[/Spoiler] c",
+			want:   []ReviewSegment{{"a ", false}, {"b", true}, {" c", false}},
+		},
+		{
+			name:   "unclosed tag hides the rest",
+			review: "a [spoiler]b\n\nc",
+			want:   []ReviewSegment{{"a ", false}, {"b\n\nc", true}},
+		},
+		{
+			name:   "adjacent spoilers merge",
+			review: "[spoiler]a[/spoiler][spoiler]b[/spoiler]",
+			want:   []ReviewSegment{{"ab", true}},
+		},
+		{
+			name:    "flagged review merges with its spoilers",
+			review:  "a [spoiler]b[/spoiler] c",
+			spoiler: true,
+			want:    []ReviewSegment{{"a b c", true}},
+		},
+		{
+			name:   "stray close tag is text",
+			review: "a [/spoiler] b",
+			want:   []ReviewSegment{{"a [/spoiler] b", false}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseReview(tt.review, tt.spoiler); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseReview(%q, %v) = %#v, want %#v", tt.review, tt.spoiler, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateSegments(t *testing.T) {
+	segments := []ReviewSegment{{"hello ", false}, {"secret", true}, {" end", false}}
+	tests := []struct {
+		name string
+		max  int
+		want []ReviewSegment
+	}{
+		{"fits", 16, segments},
+		{"room to spare", 100, segments},
+		{"cut in the first segment", 3, []ReviewSegment{{"hel...", false}}},
+		{"cut at a boundary keeps the next segment's marker", 6, []ReviewSegment{{"hello ", false}, {"...", true}}},
+		{"cut inside a spoiler stays a spoiler", 9, []ReviewSegment{{"hello ", false}, {"sec...", true}}},
+		{"zero", 0, []ReviewSegment{{"...", false}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TruncateSegments(segments, tt.max); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TruncateSegments(%d) = %#v, want %#v", tt.max, got, tt.want)
+			}
+		})
+	}
+
+	// Lengths count characters, not bytes
+	got := TruncateSegments([]ReviewSegment{{"héllo wörld", false}}, 5)
+	if want := []ReviewSegment{{"héllo...", false}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TruncateSegments multibyte = %#v, want %#v", got, want)
+	}
+}
@@ -16,11 +16,39 @@ type GenreCount struct {
 }
 
 type AuthorCount struct {
-	Author string `json:"author"`
-	Count  int    `json:"count"`
+	AuthorID int    `json:"author_id"`
+	Author   string `json:"author"`
+	Count    int    `json:"count"`
 }
 
 type MonthlyBookCount struct {
 	Month int `json:"month"`
 	Count int `json:"count"`
 }
+
+// PopularityPrior is what popularity scores are pulled towards: a work's score
+// is its mean rating after adding Weight ratings of Mean. A higher Weight
+// needs more ratings before a work can rank on its own average.
+type PopularityPrior struct {
+	Weight float64
+	Mean   float64
+}
+
+var DefaultPopularityPrior = PopularityPrior{Weight: 10, Mean: 7}
+
+// RatingHistogram is a book's rating distribution and reading status
+// breakdown across every edition of its work. Distribution[i] is the number
+// of ratings of i+1. Private entries are counted too, as no one is named.
+type RatingHistogram struct {
+	BookID       int          `json:"book_id"`
+	Distribution [10]int      `json:"distribution"`
+	TotalRatings int          `json:"total_ratings"`
+	Statuses     StatusCounts `json:"statuses"`
+}
+
+type StatusCounts struct {
+	ToRead           int `json:"to_read"`
+	CurrentlyReading int `json:"currently_reading"`
+	FinishedReading  int `json:"finished_reading"`
+	DidNotFinish     int `json:"did_not_finish"`
+}
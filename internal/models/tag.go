@@ -0,0 +1,45 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidTag = errors.New("tags must be 2-40 letters, numbers or hyphens")
+	ErrTagLimit   = errors.New("tag limit reached")
+)
+
+var (
+	tagSeparatorRe = regexp.MustCompile(`[\s_]+`)
+	tagNameRe      = regexp.MustCompile(`^[\p{L}\p{N}]+(-[\p{L}\p{N}]+)*$`)
+)
+
+type Tag struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	UseCount  int       `json:"use_count,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BookTag is a tag on a book with the number of readers who applied it.
+// Voted is only set when the request is authenticated.
+type BookTag struct {
+	Name  string `json:"name"`
+	Votes int    `json:"votes"`
+	Voted bool   `json:"voted,omitempty"`
+}
+
+// NormalizeTagName lowercases a tag and joins words with hyphens, so
+// "Found Family" and "found_family" both become "found-family".
+func NormalizeTagName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = tagSeparatorRe.ReplaceAllString(name, "-")
+	name = strings.Trim(name, "-")
+	if len([]rune(name)) < 2 || len([]rune(name)) > 40 || !tagNameRe.MatchString(name) {
+		return "", ErrInvalidTag
+	}
+	return name, nil
+}
@@ -10,10 +10,17 @@ type User struct {
 	Username     string    `json:"username"`
 	PasswordHash string    `json:"-"`
 	GoogleID     *string   `json:"google_id,omitempty"`
+	Role         string    `json:"role"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// UserPreferences are a user's display settings.
+type UserPreferences struct {
+	// RevealFinishedSpoilers shows spoilers in reviews of books the user has finished
+	RevealFinishedSpoilers bool `json:"reveal_finished_spoilers"`
+}
+
 type UserProfile struct {
 	User
 	TotalBooks            int     `json:"total_books"`
@@ -21,7 +28,11 @@ type UserProfile struct {
 	ToReadCount           int     `json:"to_read_count"`
 	CurrentlyReadingCount int     `json:"currently_reading"`
 	FinishedReadingCount  int     `json:"finished_reading"`
+	DidNotFinishCount     int     `json:"did_not_finish"`
 	FollowersCount        int     `json:"followers_count"`
 	FollowingCount        int     `json:"following_count"`
 	IsFollowing           bool    `json:"is_following"`
+	// CurrentReads are the books being read right now with their latest progress
+	CurrentReads []CurrentRead `json:"current_reads"`
+	Shelves      []Shelf       `json:"shelves"`
 }
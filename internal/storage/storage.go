@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores immutable blobs under slash separated keys. Implementations
+// must be safe for concurrent use.
+type BlobStore interface {
+	Put(key string, data []byte, contentType string) error
+	Open(key string) (Blob, error)
+	Exists(key string) (bool, error)
+	// Delete removes a blob. Deleting one that doesn't exist is not an error.
+	Delete(key string) error
+	// URL returns the public URL a blob is served from.
+	URL(key string) string
+}
+
+// Blob is an open stored object. Callers must Close it.
+type Blob interface {
+	io.ReadSeekCloser
+	ModTime() time.Time
+	Size() int64
+}
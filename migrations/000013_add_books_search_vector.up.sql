@@ -0,0 +1,22 @@
+ALTER TABLE books ADD COLUMN search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION books_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('english', COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(NEW.author, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(NEW.description, '')), 'C');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER books_search_vector_trigger
+    BEFORE INSERT OR UPDATE OF title, author, description ON books
+    FOR EACH ROW EXECUTE FUNCTION books_search_vector_update();
+
+UPDATE books SET search_vector =
+    setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(author, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(description, '')), 'C');
+
+CREATE INDEX idx_books_search_vector ON books USING GIN(search_vector);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_lists_name_trgm;
+DROP INDEX IF EXISTS idx_users_username_trgm;
+DROP INDEX IF EXISTS idx_books_author_trgm;
+DROP INDEX IF EXISTS idx_books_title_trgm;
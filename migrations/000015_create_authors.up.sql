@@ -0,0 +1,41 @@
+CREATE TYPE author_role AS ENUM ('author', 'translator', 'editor', 'illustrator');
+
+CREATE TABLE authors (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_authors_name_lower ON authors (LOWER(name));
+CREATE INDEX idx_authors_name_trgm ON authors USING GIN (name gin_trgm_ops);
+
+CREATE TABLE book_authors (
+    book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    author_id INT NOT NULL REFERENCES authors(id) ON DELETE CASCADE,
+    role author_role NOT NULL DEFAULT 'author',
+    position INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (book_id, author_id, role)
+);
+
+CREATE INDEX idx_book_authors_book_id ON book_authors(book_id);
+CREATE INDEX idx_book_authors_author_id ON book_authors(author_id);
+
+-- Split existing author strings on ';', '&', ' and ' and commas (but not ", Jr." style suffixes).
+-- books.author stays as the denormalised display string.
+CREATE TEMP TABLE split_authors AS
+SELECT b.id AS book_id, TRIM(n.name) AS name, (n.ord - 1)::int AS position
+FROM books b,
+     regexp_split_to_table(b.author, '\s*(?:;|&|\mand\M|,(?!\s*(?:jr|sr|ii|iii|iv)\M))\s*', 'i') WITH ORDINALITY AS n(name, ord)
+WHERE TRIM(n.name) <> '';
+
+INSERT INTO authors (name)
+SELECT DISTINCT ON (LOWER(name)) name FROM split_authors
+ON CONFLICT DO NOTHING;
+
+INSERT INTO book_authors (book_id, author_id, role, position)
+SELECT s.book_id, a.id, 'author', s.position
+FROM split_authors s
+JOIN authors a ON LOWER(a.name) = LOWER(s.name)
+ON CONFLICT DO NOTHING;
+
+DROP TABLE split_authors;
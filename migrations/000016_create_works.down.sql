@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_books_work_id;
+ALTER TABLE books DROP COLUMN IF EXISTS publisher;
+ALTER TABLE books DROP COLUMN IF EXISTS format;
+ALTER TABLE books DROP COLUMN IF EXISTS work_id;
+DROP TABLE IF EXISTS works;
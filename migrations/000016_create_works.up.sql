@@ -0,0 +1,19 @@
+CREATE TABLE works (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(500) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE books ADD COLUMN work_id INT REFERENCES works(id) ON DELETE RESTRICT;
+ALTER TABLE books ADD COLUMN format VARCHAR(50);
+ALTER TABLE books ADD COLUMN publisher VARCHAR(255);
+
+-- Every existing book starts out as the only edition of its own work
+ALTER TABLE works ADD COLUMN seed_book_id INT;
+INSERT INTO works (title, seed_book_id, created_at) SELECT title, id, created_at FROM books;
+UPDATE books b SET work_id = w.id FROM works w WHERE w.seed_book_id = b.id;
+ALTER TABLE works DROP COLUMN seed_book_id;
+
+ALTER TABLE books ALTER COLUMN work_id SET NOT NULL;
+CREATE INDEX idx_books_work_id ON books(work_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS series_entries;
+DROP TABLE IF EXISTS series;
@@ -0,0 +1,21 @@
+CREATE TABLE series (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Entries point at works so every edition of a book shares its place in the series.
+-- Position is fractional to allow in-between novellas (e.g. 2.5).
+CREATE TABLE series_entries (
+    series_id INT NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    work_id INT NOT NULL REFERENCES works(id) ON DELETE CASCADE,
+    position NUMERIC(6, 2) NOT NULL CHECK (position >= 0),
+    added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (series_id, work_id)
+);
+
+CREATE INDEX idx_series_entries_series_id ON series_entries(series_id, position);
+CREATE INDEX idx_series_entries_work_id ON series_entries(work_id);
+CREATE INDEX idx_series_name_trgm ON series USING GIN (name gin_trgm_ops);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS pending_book_edits;
+DROP TYPE IF EXISTS pending_edit_status;
+DROP TYPE IF EXISTS pending_edit_action;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+DROP TYPE IF EXISTS user_role;
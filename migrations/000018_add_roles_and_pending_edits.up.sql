@@ -0,0 +1,20 @@
+CREATE TYPE user_role AS ENUM ('user', 'librarian', 'moderator', 'admin');
+ALTER TABLE users ADD COLUMN role user_role NOT NULL DEFAULT 'user';
+
+CREATE TYPE pending_edit_action AS ENUM ('update', 'delete');
+CREATE TYPE pending_edit_status AS ENUM ('pending', 'approved', 'rejected');
+
+CREATE TABLE pending_book_edits (
+    id SERIAL PRIMARY KEY,
+    book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    action pending_edit_action NOT NULL,
+    changes JSONB,
+    status pending_edit_status NOT NULL DEFAULT 'pending',
+    reviewed_by INT REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_pending_book_edits_status ON pending_book_edits(status, created_at);
+CREATE INDEX idx_pending_book_edits_book_id ON pending_book_edits(book_id);
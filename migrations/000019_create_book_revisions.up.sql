@@ -0,0 +1,10 @@
+CREATE TABLE book_revisions (
+    id SERIAL PRIMARY KEY,
+    book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    changes JSONB NOT NULL,
+    reverted_from INT REFERENCES book_revisions(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_book_revisions_book_id ON book_revisions(book_id, created_at DESC);
@@ -0,0 +1,2 @@
+-- Normalizing ISBNs is not reversible; the original spellings are not kept.
+SELECT 1;
@@ -0,0 +1,57 @@
+-- Mirrors models.NormalizeISBN: strips ="..." wrapping and separators, converts
+-- valid ISBN-10s to ISBN-13 and returns NULL for anything that doesn't validate.
+CREATE FUNCTION isbn_to_13(raw TEXT) RETURNS TEXT AS $$
+DECLARE
+    s TEXT;
+    total INT := 0;
+    d INT;
+    body TEXT;
+BEGIN
+    s := upper(regexp_replace(trim(both '="' from trim(raw)), '[\s-]', '', 'g'));
+    IF s ~ '^[0-9]{9}[0-9X]$' THEN
+        FOR i IN 1..10 LOOP
+            d := CASE WHEN substr(s, i, 1) = 'X' THEN 10 ELSE substr(s, i, 1)::INT END;
+            total := total + (11 - i) * d;
+        END LOOP;
+        IF total % 11 <> 0 THEN
+            RETURN NULL;
+        END IF;
+        body := '978' || substr(s, 1, 9);
+    ELSIF s ~ '^97[89][0-9]{10}$' THEN
+        body := substr(s, 1, 12);
+    ELSE
+        RETURN NULL;
+    END IF;
+
+    total := 0;
+    FOR i IN 1..12 LOOP
+        d := substr(body, i, 1)::INT;
+        total := total + CASE WHEN i % 2 = 0 THEN d * 3 ELSE d END;
+    END LOOP;
+    body := body || ((10 - total % 10) % 10)::TEXT;
+
+    IF length(s) = 13 AND body <> s THEN
+        RETURN NULL;
+    END IF;
+    RETURN body;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- Only the first row per normalized ISBN is rewritten so the UNIQUE constraint holds;
+-- the rest are left for the duplicate finder to surface.
+WITH normalized AS (
+    SELECT DISTINCT ON (isbn_to_13(isbn)) id, isbn_to_13(isbn) AS isbn13
+    FROM books
+    WHERE isbn_to_13(isbn) IS NOT NULL
+    ORDER BY isbn_to_13(isbn), id
+)
+UPDATE books b
+SET isbn = n.isbn13
+FROM normalized n
+WHERE b.id = n.id
+  AND b.isbn <> n.isbn13
+  AND NOT EXISTS (SELECT 1 FROM books o WHERE o.isbn = n.isbn13 AND o.id <> b.id);
+
+UPDATE books SET isbn = NULL WHERE isbn = '';
+
+DROP FUNCTION isbn_to_13(TEXT);
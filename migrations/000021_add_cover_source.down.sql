@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_books_remote_cover;
+ALTER TABLE books DROP COLUMN IF EXISTS cover_fetch_failed_at;
+ALTER TABLE books DROP COLUMN IF EXISTS cover_source_url;
@@ -0,0 +1,5 @@
+ALTER TABLE books ADD COLUMN cover_source_url TEXT;
+ALTER TABLE books ADD COLUMN cover_fetch_failed_at TIMESTAMP;
+
+-- Lets the background cover importer find hotlinked covers without a full scan
+CREATE INDEX idx_books_remote_cover ON books(id) WHERE cover_url LIKE 'http%';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_genres_name_lower;
+DROP INDEX IF EXISTS idx_genres_parent_id;
+ALTER TABLE genres DROP CONSTRAINT IF EXISTS genres_not_own_parent;
+ALTER TABLE genres DROP COLUMN IF EXISTS parent_id;
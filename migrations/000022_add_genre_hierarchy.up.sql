@@ -0,0 +1,5 @@
+ALTER TABLE genres ADD COLUMN parent_id INT REFERENCES genres(id) ON DELETE SET NULL;
+ALTER TABLE genres ADD CONSTRAINT genres_not_own_parent CHECK (parent_id IS NULL OR parent_id <> id);
+
+CREATE INDEX idx_genres_parent_id ON genres(parent_id);
+CREATE UNIQUE INDEX idx_genres_name_lower ON genres(LOWER(name));
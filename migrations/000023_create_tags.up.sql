@@ -0,0 +1,19 @@
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(40) NOT NULL UNIQUE,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Tags describe the story, so votes are per work and shared by all editions
+CREATE TABLE tag_votes (
+    work_id INT NOT NULL REFERENCES works(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (work_id, tag_id, user_id)
+);
+
+CREATE INDEX idx_tag_votes_tag_id ON tag_votes(tag_id, work_id);
+CREATE INDEX idx_tag_votes_user_id ON tag_votes(user_id, created_at);
+CREATE INDEX idx_tags_created_by ON tags(created_by, created_at);
@@ -0,0 +1,27 @@
+-- Each read of a book is a session under the user's reading entry, so re-reads keep their own dates and rating
+CREATE TABLE reading_sessions (
+    id SERIAL PRIMARY KEY,
+    rating_id INT NOT NULL REFERENCES ratings(id) ON DELETE CASCADE,
+    started_at DATE,
+    finished_at DATE,
+    rating INT NOT NULL DEFAULT 0 CHECK (rating >= 0 AND rating <= 10),
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT reading_sessions_dates_check CHECK (started_at IS NULL OR finished_at IS NULL OR finished_at >= started_at)
+);
+
+CREATE INDEX idx_reading_sessions_rating_id ON reading_sessions(rating_id, finished_at);
+CREATE INDEX idx_reading_sessions_finished_at ON reading_sessions(finished_at) WHERE finished_at IS NOT NULL;
+-- Only one read can be in progress at a time
+CREATE UNIQUE INDEX idx_reading_sessions_open ON reading_sessions(rating_id) WHERE finished_at IS NULL;
+
+INSERT INTO reading_sessions (rating_id, finished_at, rating, created_at, updated_at)
+SELECT id, created_at::date, rating, created_at, updated_at
+FROM ratings
+WHERE status = 'finished_reading';
+
+INSERT INTO reading_sessions (rating_id, started_at, created_at, updated_at)
+SELECT id, created_at::date, created_at, updated_at
+FROM ratings
+WHERE status = 'currently_reading';
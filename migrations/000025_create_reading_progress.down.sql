@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS reading_progress;
+ALTER TABLE books DROP COLUMN IF EXISTS page_count;
@@ -0,0 +1,15 @@
+ALTER TABLE books ADD COLUMN page_count INT CHECK (page_count > 0);
+
+-- Progress updates on a read, kept as a history rather than a single current position
+CREATE TABLE reading_progress (
+    id SERIAL PRIMARY KEY,
+    session_id INT NOT NULL REFERENCES reading_sessions(id) ON DELETE CASCADE,
+    page INT CHECK (page >= 0),
+    percent NUMERIC(5, 2) CHECK (percent >= 0 AND percent <= 100),
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT reading_progress_position_check CHECK (page IS NOT NULL OR percent IS NOT NULL)
+);
+
+CREATE INDEX idx_reading_progress_session_id ON reading_progress(session_id, created_at DESC);
+CREATE INDEX idx_reading_progress_created_at ON reading_progress(created_at DESC);
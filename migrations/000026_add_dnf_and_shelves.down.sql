@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS shelf_books;
+DROP TABLE IF EXISTS shelves;
+
+-- Postgres can't drop an enum value, so the type is rebuilt without it
+DELETE FROM reading_sessions WHERE stopped_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_reading_sessions_open;
+CREATE UNIQUE INDEX idx_reading_sessions_open ON reading_sessions(rating_id) WHERE finished_at IS NULL;
+ALTER TABLE reading_sessions DROP COLUMN IF EXISTS stopped_at_page;
+ALTER TABLE reading_sessions DROP COLUMN IF EXISTS stopped_at;
+
+UPDATE ratings SET status = 'to_read' WHERE status = 'did_not_finish';
+ALTER TYPE reading_status RENAME TO reading_status_old;
+CREATE TYPE reading_status AS ENUM ('to_read', 'currently_reading', 'finished_reading');
+ALTER TABLE ratings ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE ratings ALTER COLUMN status TYPE reading_status USING status::text::reading_status;
+ALTER TABLE ratings ALTER COLUMN status SET DEFAULT 'finished_reading';
+DROP TYPE reading_status_old;
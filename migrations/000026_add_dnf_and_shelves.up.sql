@@ -0,0 +1,31 @@
+ALTER TYPE reading_status ADD VALUE IF NOT EXISTS 'did_not_finish';
+
+-- A read that was given up on ends with stopped_at instead of finished_at
+ALTER TABLE reading_sessions ADD COLUMN stopped_at DATE;
+ALTER TABLE reading_sessions ADD COLUMN stopped_at_page INT CHECK (stopped_at_page >= 0);
+ALTER TABLE reading_sessions ADD CONSTRAINT reading_sessions_outcome_check CHECK (finished_at IS NULL OR stopped_at IS NULL);
+ALTER TABLE reading_sessions ADD CONSTRAINT reading_sessions_stopped_check CHECK (started_at IS NULL OR stopped_at IS NULL OR stopped_at >= started_at);
+
+DROP INDEX IF EXISTS idx_reading_sessions_open;
+CREATE UNIQUE INDEX idx_reading_sessions_open ON reading_sessions(rating_id) WHERE finished_at IS NULL AND stopped_at IS NULL;
+
+-- User-defined shelves sit alongside the reading status, so a book can be both finished and "owned"
+CREATE TABLE shelves (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_shelves_user_name ON shelves(user_id, LOWER(name));
+
+CREATE TABLE shelf_books (
+    shelf_id INT NOT NULL REFERENCES shelves(id) ON DELETE CASCADE,
+    book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (shelf_id, book_id)
+);
+
+CREATE INDEX idx_shelf_books_book_id ON shelf_books(book_id);
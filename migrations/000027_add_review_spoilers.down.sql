@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS reveal_finished_spoilers;
+ALTER TABLE ratings DROP COLUMN IF EXISTS review_is_spoiler;
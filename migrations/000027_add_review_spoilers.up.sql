@@ -0,0 +1,5 @@
+-- Flags a whole review as a spoiler; parts of a review are marked inline with [spoiler]...[/spoiler]
+ALTER TABLE ratings ADD COLUMN review_is_spoiler BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Readers can opt in to seeing spoilers for books they have finished
+ALTER TABLE users ADD COLUMN reveal_finished_spoilers BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+ALTER TABLE comments DROP COLUMN IF EXISTS text_html;
+ALTER TABLE ratings DROP COLUMN IF EXISTS review_html;
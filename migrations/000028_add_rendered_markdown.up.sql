@@ -0,0 +1,4 @@
+-- Reviews and comments are written in a Markdown subset; the sanitized HTML is stored next to the source.
+-- Rows written before this are rendered when read.
+ALTER TABLE ratings ADD COLUMN review_html TEXT;
+ALTER TABLE comments ADD COLUMN text_html TEXT;
@@ -0,0 +1,15 @@
+-- Earlier versions of a review, kept when it's edited so replies still have the text they answered
+CREATE TABLE review_revisions (
+    id SERIAL PRIMARY KEY,
+    rating_id INT NOT NULL REFERENCES ratings(id) ON DELETE CASCADE,
+    review TEXT,
+    review_html TEXT,
+    review_is_spoiler BOOLEAN NOT NULL DEFAULT FALSE,
+    written_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_review_revisions_rating_id ON review_revisions(rating_id, created_at DESC);
+
+-- When the review text was last changed, unlike updated_at which also moves with status changes
+ALTER TABLE ratings ADD COLUMN edited_at TIMESTAMP;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_ratings_visibility;
+ALTER TABLE ratings DROP COLUMN IF EXISTS private_notes;
+ALTER TABLE ratings DROP COLUMN IF EXISTS visibility;
+DROP TYPE IF EXISTS entry_visibility;
@@ -0,0 +1,8 @@
+CREATE TYPE entry_visibility AS ENUM ('public', 'followers', 'private');
+
+-- Who can see a reading entry in feeds, book pages and profiles. Private entries still count towards averages.
+ALTER TABLE ratings ADD COLUMN visibility entry_visibility NOT NULL DEFAULT 'public';
+-- Notes only the entry's owner ever sees
+ALTER TABLE ratings ADD COLUMN private_notes TEXT;
+
+CREATE INDEX idx_ratings_visibility ON ratings(visibility) WHERE visibility <> 'public';
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS books_work_stats_trigger ON books;
+DROP TRIGGER IF EXISTS ratings_work_stats_trigger ON ratings;
+DROP FUNCTION IF EXISTS books_refresh_work_stats();
+DROP FUNCTION IF EXISTS ratings_refresh_work_stats();
+DROP FUNCTION IF EXISTS refresh_work_rating_stats(INT);
+DROP TABLE IF EXISTS work_rating_stats;
+DROP TABLE IF EXISTS popularity_prior;
@@ -0,0 +1,80 @@
+-- The prior that popularity scores are pulled towards: a work's score is its
+-- mean rating with weight extra ratings of mean added. The app overwrites
+-- these from its config at startup.
+CREATE TABLE popularity_prior (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    weight DOUBLE PRECISION NOT NULL CHECK (weight >= 0),
+    mean DOUBLE PRECISION NOT NULL CHECK (mean BETWEEN 1 AND 10)
+);
+INSERT INTO popularity_prior (weight, mean) VALUES (10, 7);
+
+-- Rating totals and Bayesian popularity score per work, kept current by the
+-- triggers below. Only rated entries (rating > 0) count.
+CREATE TABLE work_rating_stats (
+    work_id INT PRIMARY KEY REFERENCES works(id) ON DELETE CASCADE,
+    rating_count INT NOT NULL DEFAULT 0,
+    rating_sum BIGINT NOT NULL DEFAULT 0,
+    score DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_work_rating_stats_score ON work_rating_stats(score DESC, rating_count DESC);
+
+CREATE OR REPLACE FUNCTION refresh_work_rating_stats(wid INT) RETURNS void AS $$
+DECLARE
+    cnt INT;
+    total BIGINT;
+    w DOUBLE PRECISION;
+    m DOUBLE PRECISION;
+BEGIN
+    IF wid IS NULL OR NOT EXISTS (SELECT 1 FROM works WHERE id = wid) THEN
+        RETURN;
+    END IF;
+    SELECT COUNT(*), COALESCE(SUM(r.rating), 0) INTO cnt, total
+    FROM ratings r JOIN books b ON b.id = r.book_id
+    WHERE b.work_id = wid AND r.rating > 0;
+    SELECT weight, mean INTO w, m FROM popularity_prior;
+
+    INSERT INTO work_rating_stats (work_id, rating_count, rating_sum, score, updated_at)
+    VALUES (wid, cnt, total, COALESCE((w * m + total) / NULLIF(w + cnt, 0), m), CURRENT_TIMESTAMP)
+    ON CONFLICT (work_id) DO UPDATE SET
+        rating_count = EXCLUDED.rating_count,
+        rating_sum = EXCLUDED.rating_sum,
+        score = EXCLUDED.score,
+        updated_at = EXCLUDED.updated_at;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION ratings_refresh_work_stats() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'DELETE' THEN
+        PERFORM refresh_work_rating_stats((SELECT work_id FROM books WHERE id = NEW.book_id));
+    END IF;
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.book_id <> NEW.book_id) THEN
+        -- The book is gone already when its ratings are deleted with it; the
+        -- books trigger covers that case
+        PERFORM refresh_work_rating_stats((SELECT work_id FROM books WHERE id = OLD.book_id));
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ratings_work_stats_trigger
+    AFTER INSERT OR DELETE OR UPDATE OF rating, book_id ON ratings
+    FOR EACH ROW EXECUTE FUNCTION ratings_refresh_work_stats();
+
+-- Moving an edition to another work, or deleting it, changes both works' totals
+CREATE OR REPLACE FUNCTION books_refresh_work_stats() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        PERFORM refresh_work_rating_stats(NEW.work_id);
+    END IF;
+    PERFORM refresh_work_rating_stats(OLD.work_id);
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER books_work_stats_trigger
+    AFTER DELETE OR UPDATE OF work_id ON books
+    FOR EACH ROW EXECUTE FUNCTION books_refresh_work_stats();
+
+SELECT refresh_work_rating_stats(id) FROM works;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_ratings_book_review_language;
+ALTER TABLE ratings DROP COLUMN IF EXISTS review_language;
@@ -0,0 +1,5 @@
+-- Language the review is written in, as a lowercase primary language subtag
+-- such as "en". NULL when the reviewer didn't say.
+ALTER TABLE ratings ADD COLUMN review_language VARCHAR(3);
+
+CREATE INDEX idx_ratings_book_review_language ON ratings(book_id, review_language) WHERE review_language IS NOT NULL;
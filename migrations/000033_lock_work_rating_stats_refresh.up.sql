@@ -0,0 +1,31 @@
+CREATE OR REPLACE FUNCTION refresh_work_rating_stats(wid INT) RETURNS void AS $$
+DECLARE
+    cnt INT;
+    total BIGINT;
+    w DOUBLE PRECISION;
+    m DOUBLE PRECISION;
+BEGIN
+    IF wid IS NULL THEN
+        RETURN;
+    END IF;
+    -- Serialise recounts for the same work; without the lock two concurrent
+    -- rating changes can each count before the other commits and the later
+    -- upsert writes a stale total
+    PERFORM 1 FROM works WHERE id = wid FOR UPDATE;
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+    SELECT COUNT(*), COALESCE(SUM(r.rating), 0) INTO cnt, total
+    FROM ratings r JOIN books b ON b.id = r.book_id
+    WHERE b.work_id = wid AND r.rating > 0;
+    SELECT weight, mean INTO w, m FROM popularity_prior;
+
+    INSERT INTO work_rating_stats (work_id, rating_count, rating_sum, score, updated_at)
+    VALUES (wid, cnt, total, COALESCE((w * m + total) / NULLIF(w + cnt, 0), m), CURRENT_TIMESTAMP)
+    ON CONFLICT (work_id) DO UPDATE SET
+        rating_count = EXCLUDED.rating_count,
+        rating_sum = EXCLUDED.rating_sum,
+        score = EXCLUDED.score,
+        updated_at = EXCLUDED.updated_at;
+END
+$$ LANGUAGE plpgsql;
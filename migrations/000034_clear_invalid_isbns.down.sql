@@ -0,0 +1,2 @@
+-- Clearing invalid ISBNs is not reversible; the original values are not kept.
+SELECT 1;
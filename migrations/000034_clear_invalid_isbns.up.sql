@@ -0,0 +1,45 @@
+-- Mirrors models.NormalizeISBN: strips ="..." wrapping and separators, converts
+-- valid ISBN-10s to ISBN-13 and returns NULL for anything that doesn't validate.
+CREATE FUNCTION isbn_to_13(raw TEXT) RETURNS TEXT AS $$
+DECLARE
+    s TEXT;
+    total INT := 0;
+    d INT;
+    body TEXT;
+BEGIN
+    s := upper(regexp_replace(trim(both '="' from trim(raw)), '[\s-]', '', 'g'));
+    IF s ~ '^[0-9]{9}[0-9X]$' THEN
+        FOR i IN 1..10 LOOP
+            d := CASE WHEN substr(s, i, 1) = 'X' THEN 10 ELSE substr(s, i, 1)::INT END;
+            total := total + (11 - i) * d;
+        END LOOP;
+        IF total % 11 <> 0 THEN
+            RETURN NULL;
+        END IF;
+        body := '978' || substr(s, 1, 9);
+    ELSIF s ~ '^97[89][0-9]{10}$' THEN
+        body := substr(s, 1, 12);
+    ELSE
+        RETURN NULL;
+    END IF;
+
+    total := 0;
+    FOR i IN 1..12 LOOP
+        d := substr(body, i, 1)::INT;
+        total := total + CASE WHEN i % 2 = 0 THEN d * 3 ELSE d END;
+    END LOOP;
+    body := body || ((10 - total % 10) % 10)::TEXT;
+
+    IF length(s) = 13 AND body <> s THEN
+        RETURN NULL;
+    END IF;
+    RETURN body;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- 000020 left ISBNs that don't validate in place; they can't be matched or
+-- looked up, so they're cleared. Valid ISBNs kept in their original spelling
+-- because the normalized form was taken are left for the duplicate finder.
+UPDATE books SET isbn = NULL WHERE isbn IS NOT NULL AND isbn_to_13(isbn) IS NULL;
+
+DROP FUNCTION isbn_to_13(TEXT);
@@ -0,0 +1,10 @@
+-- Undated finished reads can't be represented without the flag, so they're dropped
+DELETE FROM reading_sessions WHERE finished AND finished_at IS NULL;
+
+DROP INDEX IF EXISTS idx_reading_sessions_open;
+CREATE UNIQUE INDEX idx_reading_sessions_open ON reading_sessions(rating_id) WHERE finished_at IS NULL AND stopped_at IS NULL;
+
+ALTER TABLE reading_sessions DROP CONSTRAINT IF EXISTS reading_sessions_finished_check;
+ALTER TABLE reading_sessions DROP CONSTRAINT reading_sessions_outcome_check;
+ALTER TABLE reading_sessions ADD CONSTRAINT reading_sessions_outcome_check CHECK (finished_at IS NULL OR stopped_at IS NULL);
+ALTER TABLE reading_sessions DROP COLUMN IF EXISTS finished;
@@ -0,0 +1,11 @@
+-- A finished read may have no finish date, e.g. one imported without a Date
+-- Read, so whether a read was finished is kept apart from when
+ALTER TABLE reading_sessions ADD COLUMN finished BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE reading_sessions SET finished = TRUE WHERE finished_at IS NOT NULL;
+
+ALTER TABLE reading_sessions DROP CONSTRAINT reading_sessions_outcome_check;
+ALTER TABLE reading_sessions ADD CONSTRAINT reading_sessions_outcome_check CHECK (NOT finished OR stopped_at IS NULL);
+ALTER TABLE reading_sessions ADD CONSTRAINT reading_sessions_finished_check CHECK (finished OR finished_at IS NULL);
+
+DROP INDEX IF EXISTS idx_reading_sessions_open;
+CREATE UNIQUE INDEX idx_reading_sessions_open ON reading_sessions(rating_id) WHERE NOT finished AND stopped_at IS NULL;
@@ -64,7 +64,7 @@
                             <h3>${book.title}</h3>
                             <p class="bookmarkd-author">${book.author}</p>
                             ${book.rating > 0 ? `<span class="bookmarkd-rating">${book.rating}/10</span>` : ''}
-                            ${book.review_snippet ? `<p class="bookmarkd-review">${book.review_snippet}</p>` : ''}
+                            ${book.spoiler ? `<p class="bookmarkd-review">Review contains spoilers</p>` : book.review_html ? `<div class="bookmarkd-review">${book.review_html}</div>` : ''}
                         </div>
                     </a>`;
             } else if (style === 'minimal') {
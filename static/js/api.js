@@ -73,19 +73,36 @@ export const api = {
         return this.request(`/books?${query}`);
     },
 
+    async getSuggestions(q, limit = 8) {
+        const query = new URLSearchParams({ q, limit }).toString();
+        return this.request(`/search/suggest?${query}`);
+    },
+
     async getBook(id) {
         return this.request(`/books/${id}`);
     },
 
     // Ratings
-    async getRatings(bookId, sortBy = 'newest') {
-        return this.request(`/books/${bookId}/ratings?sort_by=${sortBy}`);
+    // filters: { has_review, min_rating, max_rating, following, language, cursor }
+    async getRatings(bookId, sortBy = 'newest', filters = {}) {
+        const params = new URLSearchParams({ sort_by: sortBy });
+        Object.entries(filters).forEach(([key, value]) => {
+            if (value !== undefined && value !== null && value !== '' && value !== false) {
+                params.set(key, value);
+            }
+        });
+        return this.request(`/books/${bookId}/ratings?${params}`);
     },
 
-    async createRating(bookId, rating, review, status = 'finished_reading') {
+    async getRatingHistogram(bookId) {
+        return this.request(`/books/${bookId}/ratings/histogram`);
+    },
+
+    // privacy is { visibility, private_notes }; leaving either out keeps the current value
+    async createRating(bookId, rating, review, status = 'finished_reading', spoiler = false, privacy = {}, language = '') {
         return this.request(`/books/${bookId}/ratings`, {
             method: 'POST',
-            body: JSON.stringify({ rating, review, status }),
+            body: JSON.stringify({ rating, review, status, spoiler, language, ...privacy }),
         });
     },
 
@@ -95,10 +112,10 @@ export const api = {
         });
     },
 
-    async updateRating(ratingId, rating, review) {
+    async updateRating(ratingId, rating, review, spoiler = false, privacy = {}, language = '') {
         return this.request(`/ratings/${ratingId}`, {
             method: 'PATCH',
-            body: JSON.stringify({ rating, review }),
+            body: JSON.stringify({ rating, review, spoiler, language, ...privacy }),
         });
     },
 
@@ -267,6 +284,35 @@ export const api = {
     },
 };
 
+const hiddenSpoiler = `class="spoiler blur-sm cursor-pointer select-none" title="Spoiler - click to reveal" onclick="this.classList.remove('blur-sm', 'cursor-pointer', 'select-none')"`;
+
+// Blurs the spoiler spans in server-rendered (already sanitized) HTML until
+// they're clicked.
+export function renderSpoilers(html, revealed = false) {
+    if (!html || revealed) return html || '';
+    return html.replaceAll('<span class="spoiler">', `<span ${hiddenSpoiler}>`);
+}
+
+// Renders a review's HTML, hiding spoilers unless the viewer has chosen to
+// see them for this book. A review flagged as a spoiler is hidden whole.
+export function renderReview(item) {
+    if (item.spoiler && !item.spoilers_revealed) {
+        return `<div ${hiddenSpoiler}>${item.review_html || ''}</div>`;
+    }
+    return renderSpoilers(item.review_html, item.spoilers_revealed);
+}
+
+// Describes a progress update as a page, a page of the total, or a percent.
+export function getProgressText(progress) {
+    if (progress.page != null && progress.page_count) {
+        return `Page ${progress.page} of ${progress.page_count} (${Math.round(progress.percent)}%)`;
+    }
+    if (progress.page != null) {
+        return `Page ${progress.page}`;
+    }
+    return `${Math.round(progress.percent)}%`;
+}
+
 export function isLoggedIn() {
     return !!localStorage.getItem('token');
 }
@@ -1,4 +1,4 @@
-import { api, isLoggedIn, getCurrentUserId, updateNavigation } from './api.js';
+import { api, isLoggedIn, getCurrentUserId, updateNavigation, renderReview, renderSpoilers } from './api.js';
 
 updateNavigation();
 
@@ -96,6 +96,12 @@ async function loadExistingRating() {
             if (rating.review) {
                 document.getElementById('reviewText').value = rating.review;
             }
+            document.getElementById('reviewSpoiler').checked = !!rating.spoiler;
+            if (rating.language) {
+                document.getElementById('reviewLanguage').value = rating.language;
+            }
+            document.getElementById('entryVisibility').value = rating.visibility || 'public';
+            document.getElementById('privateNotes').value = rating.private_notes || '';
 
             // Change submit button text
             document.querySelector('#ratingForm button[type="submit"]').textContent = 'Update Rating';
@@ -106,20 +112,133 @@ async function loadExistingRating() {
     }
 }
 
-async function loadRatings(sortBy = 'newest') {
+async function loadHistogram() {
     try {
-        const data = await api.getRatings(bookId, sortBy);
+        const histogram = await api.getRatingHistogram(bookId);
+        const container = document.getElementById('ratingHistogram');
+        if (histogram.total_ratings === 0) {
+            container.classList.add('hidden');
+            return;
+        }
+
+        // Highest rating on top, bars scaled to the most common rating
+        const most = Math.max(...histogram.distribution);
+        document.getElementById('histogramBars').innerHTML = histogram.distribution
+            .map((count, i) => ({ rating: i + 1, count }))
+            .reverse()
+            .map(({ rating, count }) => `
+                <div class="histogram-row" title="${count} rating${count !== 1 ? 's' : ''} of ${rating}">
+                    <span class="histogram-label">${rating}</span>
+                    <div class="histogram-track"><div class="histogram-bar" style="width: ${most ? (count / most) * 100 : 0}%"></div></div>
+                    <span class="histogram-count">${count}</span>
+                </div>
+            `).join('');
+
+        const s = histogram.statuses;
+        const parts = [
+            `${s.to_read} want to read`,
+            `${s.currently_reading} reading`,
+            `${s.finished_reading} read`,
+        ];
+        if (s.did_not_finish) {
+            parts.push(`${s.did_not_finish} did not finish`);
+        }
+        document.getElementById('statusBreakdown').textContent = parts.join(' · ');
+        container.classList.remove('hidden');
+    } catch (error) {
+        console.error('Error loading rating histogram:', error);
+    }
+}
+
+let reviewSort = 'newest';
+let reviewCursor = '';
+
+function reviewFilters() {
+    return {
+        has_review: document.getElementById('filterHasReview').checked,
+        min_rating: document.getElementById('filterMinRating').value,
+        max_rating: document.getElementById('filterMaxRating').value,
+        following: isLoggedIn() && document.getElementById('filterFollowing').checked,
+        language: document.getElementById('filterLanguage').value.trim(),
+    };
+}
+
+function renderReviewCard(rating) {
+    const isOwn = currentUserId && rating.user_id === currentUserId;
+
+    return `
+        <div class="bg-gray-800 rounded-lg p-6">
+            <div class="flex justify-between items-start mb-3">
+                <div>
+                    <a href="user-profile.html?id=${rating.user_id}" class="font-semibold text-blue-400 hover:text-blue-300">${rating.username}</a>
+                    <div class="flex items-center gap-2 mt-1">
+                        <span class="rating-badge">${rating.rating}/10</span>
+                        <span class="text-gray-500 text-sm">${new Date(rating.created_at).toLocaleDateString()}</span>
+                        ${rating.edited_at ? `<span class="text-gray-500 text-xs" title="Edited ${new Date(rating.edited_at).toLocaleDateString()}">(edited)</span>` : ''}
+                    </div>
+                </div>
+            </div>
+            
+            <div class="review-body text-gray-300 mb-4">${renderReview(rating)}</div>
+            
+            <div class="flex items-center gap-4 text-sm">
+                ${!isOwn ? `
+                    <button onclick="toggleLike(${rating.id})" 
+                            class="flex items-center gap-1 ${rating.liked_by_user ? 'text-red-500' : 'text-gray-400'} hover:text-red-400">
+                        <span id="like-icon-${rating.id}">${rating.liked_by_user ? '❤️' : '🤍'}</span>
+                        <span id="like-count-${rating.id}">${rating.like_count}</span>
+                    </button>
+                ` : `
+                    <span class="flex items-center gap-1 text-gray-600">
+                        <span>❤️</span>
+                        <span>${rating.like_count}</span>
+                    </span>
+                `}
+                <button onclick="toggleComments(${rating.id})" class="text-gray-400 hover:text-blue-400">
+                    💬 <span id="comment-count-${rating.id}">${rating.comment_count}</span>
+                </button>
+            </div>
+            
+            <div id="comments-${rating.id}" class="hidden mt-4 pl-4 border-l-2 border-gray-700">
+                <div id="comments-list-${rating.id}"></div>
+                ${isLoggedIn() ? `
+                    <div class="mt-4">
+                        <textarea id="comment-input-${rating.id}" 
+                                  class="input-field w-full" 
+                                  rows="2" 
+                                  placeholder="Add a comment..."></textarea>
+                        <button onclick="addComment(${rating.id})" 
+                                class="btn-primary mt-2">Post Comment</button>
+                    </div>
+                ` : ''}
+            </div>
+        </div>
+    `;
+}
+
+// loadRatings reloads the rating stats and the first page of reviews; with
+// more set it appends the next page instead
+async function loadRatings(sortBy = reviewSort, more = false) {
+    reviewSort = sortBy;
+    if (!more) {
+        reviewCursor = '';
+        loadHistogram();
+    }
+    try {
+        const data = await api.getRatings(bookId, sortBy, { ...reviewFilters(), cursor: reviewCursor });
 
         document.getElementById('avgRating').textContent = data.average_rating.toFixed(1);
         document.getElementById('totalRatings').textContent = `${data.total_ratings} rating${data.total_ratings !== 1 ? 's' : ''}`;
 
         const container = document.getElementById('reviewsList');
         const noReviews = document.getElementById('noReviews');
+        const loadMore = document.getElementById('loadMoreReviews');
 
-        // Filter out ratings without reviews
-        const ratingsWithReviews = data.ratings.filter(r => r.review && r.review.trim());
+        reviewCursor = data.next_cursor || '';
+        loadMore.classList.toggle('hidden', !reviewCursor);
 
-        if (ratingsWithReviews.length === 0) {
+        if (!more && data.ratings.length === 0) {
+            container.innerHTML = '';
             container.classList.add('hidden');
             noReviews.classList.remove('hidden');
             return;
@@ -128,57 +247,12 @@ async function loadRatings(sortBy = 'newest') {
         container.classList.remove('hidden');
         noReviews.classList.add('hidden');
 
-        container.innerHTML = ratingsWithReviews.map(rating => {
-            const isOwn = currentUserId && rating.user_id === currentUserId;
-
-            return `
-                <div class="bg-gray-800 rounded-lg p-6">
-                    <div class="flex justify-between items-start mb-3">
-                        <div>
-                            <a href="user-profile.html?id=${rating.user_id}" class="font-semibold text-blue-400 hover:text-blue-300">${rating.username}</a>
-                            <div class="flex items-center gap-2 mt-1">
-                                <span class="rating-badge">${rating.rating}/10</span>
-                                <span class="text-gray-500 text-sm">${new Date(rating.created_at).toLocaleDateString()}</span>
-                            </div>
-                        </div>
-                    </div>
-                    
-                    <p class="text-gray-300 mb-4">${rating.review}</p>
-                    
-                    <div class="flex items-center gap-4 text-sm">
-                        ${!isOwn ? `
-                            <button onclick="toggleLike(${rating.id})" 
-                                    class="flex items-center gap-1 ${rating.liked_by_user ? 'text-red-500' : 'text-gray-400'} hover:text-red-400">
-                                <span id="like-icon-${rating.id}">${rating.liked_by_user ? '❤️' : '🤍'}</span>
-                                <span id="like-count-${rating.id}">${rating.like_count}</span>
-                            </button>
-                        ` : `
-                            <span class="flex items-center gap-1 text-gray-600">
-                                <span>❤️</span>
-                                <span>${rating.like_count}</span>
-                            </span>
-                        `}
-                        <button onclick="toggleComments(${rating.id})" class="text-gray-400 hover:text-blue-400">
-                            💬 <span id="comment-count-${rating.id}">${rating.comment_count}</span>
-                        </button>
-                    </div>
-                    
-                    <div id="comments-${rating.id}" class="hidden mt-4 pl-4 border-l-2 border-gray-700">
-                        <div id="comments-list-${rating.id}"></div>
-                        ${isLoggedIn() ? `
-                            <div class="mt-4">
-                                <textarea id="comment-input-${rating.id}" 
-                                          class="input-field w-full" 
-                                          rows="2" 
-                                          placeholder="Add a comment..."></textarea>
-                                <button onclick="addComment(${rating.id})" 
-                                        class="btn-primary mt-2">Post Comment</button>
-                            </div>
-                        ` : ''}
-                    </div>
-                </div>
-            `;
-        }).join('');
+        const html = data.ratings.map(renderReviewCard).join('');
+        if (more) {
+            container.insertAdjacentHTML('beforeend', html);
+        } else {
+            container.innerHTML = html;
+        }
 
     } catch (error) {
         console.error('Error loading ratings:', error);
@@ -231,12 +305,14 @@ document.querySelectorAll('.status-btn').forEach(btn => {
         try {
             const rating = selectedRating || 0;
             const review = document.getElementById('reviewText').value;
-            await api.createRating(bookId, rating, review, selectedStatus);
+            const spoiler = document.getElementById('reviewSpoiler').checked;
+            await api.createRating(bookId, rating, review, selectedStatus, spoiler, entryPrivacy(), reviewLanguage());
 
             const statusText = {
                 'to_read': 'Added to Want to Read',
                 'currently_reading': 'Added to Currently Reading',
-                'finished_reading': 'Marked as Finished'
+                'finished_reading': 'Marked as Finished',
+                'did_not_finish': 'Marked as Did Not Finish'
             };
             showToast(statusText[selectedStatus]);
             loadRatings();
@@ -247,6 +323,17 @@ document.querySelectorAll('.status-btn').forEach(btn => {
     });
 });
 
+function reviewLanguage() {
+    return document.getElementById('reviewLanguage').value.trim();
+}
+
+function entryPrivacy() {
+    return {
+        visibility: document.getElementById('entryVisibility').value,
+        private_notes: document.getElementById('privateNotes').value,
+    };
+}
+
 // Rating button handlers
 document.querySelectorAll('.rating-btn').forEach(btn => {
     btn.addEventListener('click', function() {
@@ -275,9 +362,10 @@ document.getElementById('ratingForm').addEventListener('submit', async (e) => {
     // Default to finished_reading if no status selected
     const status = selectedStatus || 'finished_reading';
     const review = document.getElementById('reviewText').value;
+    const spoiler = document.getElementById('reviewSpoiler').checked;
 
     try {
-        await api.createRating(bookId, selectedRating, review, status);
+        await api.createRating(bookId, selectedRating, review, status, spoiler, entryPrivacy(), reviewLanguage());
         showToast(hasExistingRating ? 'Rating updated!' : 'Rating submitted!');
 
         // Update UI to show selected status if it was defaulted
@@ -377,6 +465,18 @@ document.getElementById('sortSelect').addEventListener('change', (e) => {
     loadRatings(e.target.value);
 });
 
+// New reviews default to the browser's language
+document.getElementById('reviewLanguage').value = (navigator.language || '').split('-')[0];
+
+// Review filters
+if (isLoggedIn()) {
+    document.getElementById('filterFollowingLabel').classList.remove('hidden');
+}
+['filterHasReview', 'filterMinRating', 'filterMaxRating', 'filterFollowing', 'filterLanguage'].forEach(id => {
+    document.getElementById(id).addEventListener('change', () => loadRatings());
+});
+document.getElementById('loadMoreReviews').addEventListener('click', () => loadRatings(reviewSort, true));
+
 // Like/Unlike
 window.toggleLike = async function(ratingId) {
     if (!isLoggedIn()) {
@@ -433,7 +533,7 @@ async function loadComments(ratingId) {
                     <div class="flex justify-between items-start">
                         <div>
                             <a href="user-profile.html?id=${comment.user_id}" class="text-blue-400 text-sm font-semibold">${comment.username}</a>
-                            <p class="text-gray-300 text-sm mt-1">${comment.text}</p>
+                            <div class="review-body text-gray-300 text-sm mt-1">${renderSpoilers(comment.text_html)}</div>
                             <span class="text-gray-500 text-xs">${new Date(comment.created_at).toLocaleDateString()}</span>
                         </div>
                         ${isOwn ? `
@@ -146,6 +146,34 @@ document.getElementById('searchInput').addEventListener('keypress', (e) => {
     }
 });
 
+// Typeahead suggestions, debounced so we don't hit the API on every keystroke
+let suggestTimer;
+document.getElementById('searchInput').addEventListener('input', (e) => {
+    clearTimeout(suggestTimer);
+    const q = e.target.value.trim();
+    const datalist = document.getElementById('searchSuggestions');
+    if (q.length < 2) {
+        datalist.innerHTML = '';
+        return;
+    }
+    suggestTimer = setTimeout(async () => {
+        try {
+            const suggestions = await api.getSuggestions(q);
+            datalist.innerHTML = suggestions
+                .filter(s => s.type === 'book' || s.type === 'author')
+                .map(s => {
+                    const option = document.createElement('option');
+                    option.value = s.label;
+                    option.label = s.type === 'book' ? `Book · ${s.detail}` : 'Author';
+                    return option.outerHTML;
+                })
+                .join('');
+        } catch (error) {
+            console.error('Error loading suggestions:', error);
+        }
+    }, 200);
+});
+
 document.getElementById('genreFilter').addEventListener('change', (e) => {
     currentGenre = e.target.value;
     currentTab = 'all';
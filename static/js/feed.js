@@ -1,4 +1,4 @@
-import { api, isLoggedIn, updateNavigation, getCurrentUserId } from './api.js';
+import { api, isLoggedIn, updateNavigation, getCurrentUserId, renderReview, renderSpoilers, getProgressText } from './api.js';
 
 updateNavigation();
 
@@ -114,7 +114,7 @@ function renderFeedItem(item) {
                                       onclick="window.location.href='user-profile.html?id=${item.user_id}'">
                                     ${item.username}
                                 </span>
-                                ${getStatusText(item.status)}
+                                ${item.type === 'progress' ? 'updated their progress on' : getStatusText(item.status)}
                             </p>
                             <h3 class="font-bold text-lg mt-1 cursor-pointer hover:text-blue-400 transition"
                                 onclick="window.location.href='book-detail.html?id=${item.book_id}'">${item.book_title}</h3>
@@ -123,7 +123,9 @@ function renderFeedItem(item) {
                         ${item.rating > 0 ? `<div class="rating-badge">${item.rating}/10</div>` : ''}
                     </div>
                     
-                    ${hasReview ? `<p class="text-gray-400 text-sm mt-2">${item.review}</p>` : ''}
+                    ${item.progress ? `<p class="text-gray-400 text-sm mt-2">${getProgressText(item.progress)}</p>` : ''}
+                    ${item.progress && item.progress.comment_html ? `<div class="review-body text-gray-400 text-sm mt-1">${item.progress.comment_html}</div>` : ''}
+                    ${hasReview ? `<div class="review-body text-gray-400 text-sm mt-2">${renderReview(item)}</div>` : ''}
                     
                     ${hasReview ? `
                     <!-- Like and Comment buttons (only for reviews) -->
@@ -142,7 +144,7 @@ function renderFeedItem(item) {
                             </svg>
                             <span>Comments (<span class="comment-count">${item.comment_count || 0}</span>)</span>
                         </button>
-                        <p class="text-xs text-gray-500 ml-auto">${formatDate(item.created_at)}</p>
+                        <p class="text-xs text-gray-500 ml-auto">${formatDate(item.created_at)}${item.edited_at ? ` · <span title="Edited ${formatDate(item.edited_at)}">edited</span>` : ''}</p>
                     </div>
 
                     <!-- Comments section -->
@@ -265,7 +267,7 @@ async function loadComments(reviewId) {
                 <div class="flex justify-between items-start bg-gray-700/30 p-2 rounded">
                     <div class="flex-1">
                         <p class="text-sm font-bold text-blue-400 cursor-pointer hover:underline" onclick="window.location.href='user-profile.html?id=${c.user_id}'">${c.username}</p>
-                        <p class="text-sm text-gray-300">${c.text}</p>
+                        <div class="review-body text-sm text-gray-300">${renderSpoilers(c.text_html)}</div>
                     </div>
                     ${currentUserId === c.user_id ? `
                         <button class="delete-comment-btn text-red-400 hover:text-red-300 text-xs" data-comment-id="${c.id}" data-review-id="${reviewId}">Delete</button>
@@ -299,6 +301,7 @@ function getStatusText(status) {
         case 'to_read': return 'wants to read';
         case 'currently_reading': return 'is reading';
         case 'finished_reading': return 'finished reading';
+        case 'did_not_finish': return 'stopped reading';
         default: return 'rated';
     }
 }
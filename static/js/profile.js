@@ -1,4 +1,4 @@
-import { api, isLoggedIn, updateNavigation, logout } from './api.js';
+import { api, isLoggedIn, updateNavigation, logout, renderReview, getProgressText } from './api.js';
 
 if (!isLoggedIn()) {
     window.location.href = 'login.html';
@@ -21,6 +21,7 @@ async function loadProfile(status = '') {
 
         document.getElementById('username').textContent = profile.username;
         document.getElementById('email').textContent = profile.email;
+        loadCurrentReads(profile.user_id);
 
         loading.classList.add('hidden');
 
@@ -68,7 +69,7 @@ async function loadProfile(status = '') {
                                 ${r.rating > 0 ? `<div class="rating-badge">${r.rating}/10</div>` : ''}
                                 <span class="text-xs text-gray-500">${getStatusLabel(r.status)}</span>
                             </div>
-                            ${r.review ? `<p class="text-gray-400 text-sm">${r.review}</p>` : ''}
+                            ${r.review ? `<div class="review-body text-gray-400 text-sm">${renderReview(r)}</div>` : ''}
                             <p class="text-xs text-gray-500 mt-2">${new Date(r.created_at).toLocaleDateString()}</p>
                         </div>
                     </div>
@@ -91,11 +92,39 @@ async function loadProfile(status = '') {
     }
 }
 
+async function loadCurrentReads(userId) {
+    const section = document.getElementById('currentReads');
+    try {
+        const { current_reads: reads } = await api.getUserProfile(userId);
+        if (!reads || reads.length === 0) {
+            section.classList.add('hidden');
+            return;
+        }
+        document.getElementById('currentReadsList').innerHTML = reads.map(read => `
+            <div class="flex gap-4 items-center cursor-pointer"
+                 onclick="window.location.href='book-detail.html?id=${read.book_id}'">
+                <img src="${read.cover_url || 'https://via.placeholder.com/100x150'}"
+                     alt="${read.title}"
+                     class="w-12 h-18 object-cover rounded">
+                <div class="flex-1">
+                    <h3 class="font-bold">${read.title}</h3>
+                    <p class="text-gray-400 text-sm">${read.author}</p>
+                    ${read.progress ? `<p class="text-xs text-gray-500 mt-1">${getProgressText(read.progress)}</p>` : ''}
+                </div>
+            </div>
+        `).join('');
+        section.classList.remove('hidden');
+    } catch (error) {
+        console.error('Error loading current reads:', error);
+    }
+}
+
 function getStatusLabel(status) {
     switch(status) {
         case 'to_read': return '📚 To Read';
         case 'currently_reading': return '📖 Reading';
         case 'finished_reading': return '✅ Finished';
+        case 'did_not_finish': return '⏹️ Did Not Finish';
         default: return '';
     }
 }
@@ -114,7 +143,8 @@ document.querySelectorAll('.tab-btn').forEach(btn => {
             'all': 'Your Books',
             'to_read': 'Want to Read',
             'currently_reading': 'Currently Reading',
-            'finished_reading': 'Finished Books'
+            'finished_reading': 'Finished Books',
+            'did_not_finish': 'Did Not Finish'
         };
         document.getElementById('sectionTitle').textContent = titles[currentStatus];
 